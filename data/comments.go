@@ -1,7 +1,18 @@
 package data
 
+import (
+	"strings"
+	"unicode"
+)
+
+// DefaultCommentTags are the directives parsed from comments unless
+// Config.CommentTags is given
+var DefaultCommentTags = []string{"deprecated", "example", "since", "internal"}
+
 // Comments describe code comments
 type Comments struct {
+	tags []string // Directives to parse, nil for DefaultCommentTags
+
 	Leading         string
 	Trailing        string
 	LeadingDetached []string
@@ -10,3 +21,172 @@ type Comments struct {
 func (c *Comments) String() string {
 	return c.Leading
 }
+
+// Text returns the leading comment with its indentation and `*` gutters
+// removed, excluding any directives
+func (c Comments) Text() string {
+	text, _ := c.parse()
+	return text
+}
+
+// Summary returns the first paragraph of the comment's text, joined into a
+// single line
+func (c Comments) Summary() string {
+	text, _ := c.parse()
+	summary := strings.SplitN(text, "\n\n", 2)[0]
+	return strings.Join(strings.Fields(summary), " ")
+}
+
+// Body returns the comment's text following the summary
+func (c Comments) Body() string {
+	text, _ := c.parse()
+	parts := strings.SplitN(text, "\n\n", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.Trim(parts[1], "\n")
+}
+
+// Directives returns the values of the `@tag value` directives in the leading
+// comment, keyed by tag.  Only the tags listed in Config.CommentTags are
+// parsed, others are left in the comment's text.  A directive's value continues
+// until a blank line or the next line starting with `@`, and repeated
+// directives are joined by newlines.
+//
+// Example:
+//
+//	{{ with .Comments.Directives.since }}Available since {{ . }}{{ end }}
+func (c Comments) Directives() map[string]string {
+	_, directives := c.parse()
+	return directives
+}
+
+// Directive returns the value of the named directive, or an empty string if
+// the comment doesn't contain it
+func (c Comments) Directive(tag string) string {
+	_, directives := c.parse()
+	return directives[tag]
+}
+
+// HasDirective returns true if the comment contains the named directive, even
+// if it has no value, ie `@internal`
+func (c Comments) HasDirective(tag string) bool {
+	_, directives := c.parse()
+	_, found := directives[tag]
+	return found
+}
+
+// parse splits the leading comment's cleaned lines into text and directives
+func (c Comments) parse() (string, map[string]string) {
+	tags := c.tags
+	if tags == nil {
+		tags = DefaultCommentTags
+	}
+
+	var (
+		text       = make([]string, 0)
+		directives = map[string]string{}
+		current    = "" // Tag of the directive being read, if any
+	)
+	for _, line := range cleanComment(c.Leading) {
+		if tag, value, found := parseDirective(line, tags); found {
+			directives[tag] = joinDirective(directives[tag], value)
+			current = tag
+			continue
+		}
+
+		if current != "" && line != "" && !strings.HasPrefix(line, "@") {
+			directives[current] = joinDirective(directives[current], line)
+			continue
+		}
+		current = ""
+		text = append(text, line)
+	}
+
+	return strings.Trim(collapseBlankLines(text), "\n"), directives
+}
+
+// parseDirective returns the tag and value of a line beginning with one of the
+// given `@tag`s
+func parseDirective(line string, tags []string) (string, string, bool) {
+	if !strings.HasPrefix(line, "@") {
+		return "", "", false
+	}
+	for _, tag := range tags {
+		rest := strings.TrimPrefix(line, "@"+tag)
+		if rest == line {
+			continue
+		}
+		if rest != "" && !unicode.IsSpace(rune(rest[0])) {
+			continue
+		}
+		return tag, strings.TrimSpace(rest), true
+	}
+	return "", "", false
+}
+
+// joinDirective appends a line to a directive's value
+func joinDirective(value, line string) string {
+	if value == "" || line == "" {
+		return value + line
+	}
+	return value + "\n" + line
+}
+
+// cleanComment splits a comment into lines, removing the `*` gutter of block
+// comments and the indentation shared by all lines
+func cleanComment(comment string) []string {
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+	}
+
+	// Block comments are commonly written with a `*` at the start of each line
+	gutter := false
+	for _, line := range lines {
+		trimmed := strings.TrimLeftFunc(line, unicode.IsSpace)
+		if trimmed == "" {
+			continue
+		}
+		if !strings.HasPrefix(trimmed, "*") {
+			gutter = false
+			break
+		}
+		gutter = true
+	}
+	if gutter {
+		for i, line := range lines {
+			lines[i] = strings.TrimPrefix(strings.TrimLeftFunc(line, unicode.IsSpace), "*")
+		}
+	}
+
+	indent := -1
+	for _, line := range lines {
+		trimmed := strings.TrimLeftFunc(line, unicode.IsSpace)
+		if trimmed == "" {
+			continue
+		}
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		}
+	}
+
+	return lines
+}
+
+// collapseBlankLines joins lines, replacing runs of blank lines with one
+func collapseBlankLines(lines []string) string {
+	out := make([]string, 0, len(lines))
+	for i, line := range lines {
+		if line == "" && i > 0 && lines[i-1] == "" {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
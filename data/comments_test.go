@@ -0,0 +1,70 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommentsParse(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		comments   Comments
+		text       string
+		directives map[string]string
+	}{
+		{
+			name:       "line comment",
+			comments:   Comments{Leading: " Summary line.\n\n More text.\n"},
+			text:       "Summary line.\n\nMore text.",
+			directives: map[string]string{},
+		},
+		{
+			name:       "block comment gutter",
+			comments:   Comments{Leading: "*\n * Summary.\n *\n *   Indented.\n"},
+			text:       "Summary.\n\n  Indented.",
+			directives: map[string]string{},
+		},
+		{
+			name:       "blank lines",
+			comments:   Comments{Leading: " Summary.\n\n\n\n Body.\n"},
+			text:       "Summary.\n\nBody.",
+			directives: map[string]string{},
+		},
+		{
+			name:     "directives",
+			comments: Comments{Leading: " Summary.\n @since 1.2\n @deprecated use\n   Other instead\n\n @internal\n @since 1.3\n"},
+			text:     "Summary.",
+			directives: map[string]string{
+				"since":      "1.2\n1.3",
+				"deprecated": "use\n  Other instead",
+				"internal":   "",
+			},
+		},
+		{
+			name:       "unknown tags",
+			comments:   Comments{Leading: " Summary.\n @todo fix\n @sinceforever\n"},
+			text:       "Summary.\n@todo fix\n@sinceforever",
+			directives: map[string]string{},
+		},
+		{
+			name:       "configured tags",
+			comments:   Comments{tags: []string{"todo"}, Leading: " Summary.\n @todo fix\n @since 1.2\n"},
+			text:       "Summary.\n@since 1.2",
+			directives: map[string]string{"todo": "fix"},
+		},
+		{
+			name:       "no tags",
+			comments:   Comments{tags: []string{}, Leading: " @since 1.2\n"},
+			text:       "@since 1.2",
+			directives: map[string]string{},
+		},
+	} {
+		text, directives := test.comments.parse()
+		if text != test.text {
+			t.Errorf("%s: expected text %q, got %q", test.name, test.text, text)
+		}
+		if !reflect.DeepEqual(directives, test.directives) {
+			t.Errorf("%s: expected directives %q, got %q", test.name, test.directives, directives)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
 	plugin "google.golang.org/protobuf/types/pluginpb"
 )
 
@@ -13,31 +14,64 @@ import (
 type Data struct {
 	enums      map[enumID]*Enum
 	enumValues map[enumValueID]*EnumValue
+	extensions map[extensionID]*Extension
 	fields     map[fieldID]*Field
 	files      map[fileID]*File
 	messages   map[messageID]*Message
 	methods    map[methodID]*Method
 	oneofs     map[oneofID]*Oneof
 	services   map[serviceID]*Service
+	packages   map[string]bool // Fully-qualified packages, including their parents
 
 	filesToGenerate map[string]bool
 	fileCount       int
 	msgCount        int
 	fieldCount      int
+	extensionCount  int
+	enumCount       int
+	enumValueCount  int
+	oneofCount      int
+	serviceCount    int
+	methodCount     int
+
+	protoFiles []*descriptor.FileDescriptorProto
+	types      *dynamicpb.Types // Types defined by protoFiles, built on first use by Option
+
+	commentTags []string // Directives parsed from comments, nil for DefaultCommentTags
+
+	// Params contains the key=value pairs passed as the plugin's parameter
+	Params map[string]string
+}
+
+// Config changes how New describes a request
+type Config struct {
+	// CommentTags lists the `@tag` directives parsed from comments.  A nil
+	// slice selects DefaultCommentTags.
+	CommentTags []string
+}
+
+// New returns a new Data describing the code generator request.  It returns an
+// *UnresolvedError if the request refers to a type it doesn't define.
+func New(req *plugin.CodeGeneratorRequest) (*Data, error) {
+	return NewWithConfig(req, Config{})
 }
 
-// New returns a new Data describing the code generator request
-func New(req *plugin.CodeGeneratorRequest) *Data {
+// NewWithConfig is New, with the given Config
+func NewWithConfig(req *plugin.CodeGeneratorRequest, config Config) (*Data, error) {
 	data := &Data{
 		enums:           map[enumID]*Enum{},
 		enumValues:      map[enumValueID]*EnumValue{},
+		extensions:      map[extensionID]*Extension{},
 		fields:          map[fieldID]*Field{},
 		files:           make(map[fileID]*File, len(req.ProtoFile)),
 		messages:        map[messageID]*Message{},
 		methods:         map[methodID]*Method{},
 		oneofs:          map[oneofID]*Oneof{},
 		services:        map[serviceID]*Service{},
+		packages:        map[string]bool{},
 		filesToGenerate: make(map[string]bool, len(req.FileToGenerate)),
+		protoFiles:      req.ProtoFile,
+		commentTags:     config.CommentTags,
 	}
 
 	// Build files to generate index
@@ -47,14 +81,17 @@ func New(req *plugin.CodeGeneratorRequest) *Data {
 
 	// Merge files & their contents
 	for _, file := range req.ProtoFile {
-		data.mergeFile(file)
+		if err := data.mergeFile(file); err != nil {
+			return nil, err
+		}
 	}
 
-	return data
+	return data, nil
 }
 
 func (d *Data) mergeEnum(f fileID, m messageID, desc *descriptor.EnumDescriptorProto, path string) enumID {
 	enum := &Enum{
+		idx:      d.enumCount,
 		data:     d,
 		file:     f,
 		parent:   m,
@@ -63,7 +100,9 @@ func (d *Data) mergeEnum(f fileID, m messageID, desc *descriptor.EnumDescriptorP
 		Meta:     newEnumMetadata(desc.Options),
 		Options:  derefEnumOptions(desc.Options),
 		Comments: d.comments(f, path),
+		Location: d.location(f, path),
 	}
+	d.enumCount++
 
 	if m == "" {
 		enum.id = enumID(fmt.Sprintf(".%s.%s", d.files[f].Package, *desc.Name))
@@ -85,6 +124,7 @@ func (d *Data) mergeEnum(f fileID, m messageID, desc *descriptor.EnumDescriptorP
 
 func (d *Data) mergeEnumValue(f fileID, e enumID, desc *descriptor.EnumValueDescriptorProto, path string) enumValueID {
 	enumValue := &EnumValue{
+		idx:      d.enumValueCount,
 		id:       enumValueID(fmt.Sprintf("%s:%s", e, *desc.Name)),
 		data:     d,
 		parent:   e,
@@ -92,13 +132,53 @@ func (d *Data) mergeEnumValue(f fileID, e enumID, desc *descriptor.EnumValueDesc
 		Meta:     newEnumValueMetadata(desc.Options),
 		Options:  derefEnumValueOptions(desc.Options),
 		Comments: d.comments(f, path),
+		Location: d.location(f, path),
 		Number:   *desc.Number,
 	}
+	d.enumValueCount++
 
 	d.enumValues[enumValue.id] = enumValue
 	return enumValue.id
 }
 
+func (d *Data) mergeExtension(f fileID, m messageID, desc *descriptor.FieldDescriptorProto, path string) extensionID {
+	extension := &Extension{
+		idx:          d.extensionCount,
+		data:         d,
+		file:         f,
+		scope:        m,
+		extendee:     messageID(desc.GetExtendee()),
+		Name:         *desc.Name,
+		Meta:         newFieldMetadata(desc.Options),
+		Options:      derefFieldOptions(desc.Options),
+		Comments:     d.comments(f, path),
+		Location:     d.location(f, path),
+		Number:       *desc.Number,
+		Label:        *desc.Label,
+		Type:         *desc.Type,
+		DefaultValue: toString(desc.DefaultValue, ""),
+		JSONName:     toString(desc.JsonName, ""),
+	}
+	d.extensionCount++
+
+	if m == "" {
+		extension.id = extensionID(fmt.Sprintf(".%s.%s", d.files[f].Package, *desc.Name))
+	} else {
+		// Nested extensions use their scope's name as a prefix
+		extension.id = extensionID(fmt.Sprintf("%s.%s", m, *desc.Name))
+	}
+
+	if extension.Type == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+		extension.typeMessage = messageID(desc.GetTypeName())
+	}
+	if extension.Type == descriptor.FieldDescriptorProto_TYPE_ENUM {
+		extension.typeEnum = enumID(desc.GetTypeName())
+	}
+
+	d.extensions[extension.id] = extension
+	return extension.id
+}
+
 func (d *Data) mergeField(f fileID, m *Message, desc *descriptor.FieldDescriptorProto, path string) fieldID {
 	field := &Field{
 		idx:          d.fieldCount,
@@ -110,22 +190,26 @@ func (d *Data) mergeField(f fileID, m *Message, desc *descriptor.FieldDescriptor
 		Meta:         newFieldMetadata(desc.Options),
 		Options:      derefFieldOptions(desc.Options),
 		Comments:     d.comments(f, path),
+		Location:     d.location(f, path),
 		Number:       *desc.Number,
 		Label:        *desc.Label,
 		DefaultValue: toString(desc.DefaultValue, ""),
-		JSONName:     *desc.JsonName,
+		JSONName:     toString(desc.JsonName, ""),
 	}
 	d.fieldCount++
 	d.fields[field.id] = field
 
-	// Associate oneof fields with their associated Oneof
+	// Associate oneof fields with their associated Oneof.  Proto3 optional
+	// fields are the only member of a synthetic oneof.
 	if desc.OneofIndex != nil {
 		id := m.oneofs[*desc.OneofIndex]
 		field.oneof = id
+		field.proto3Optional = desc.GetProto3Optional()
 		d.oneofs[id].fields = append(d.oneofs[id].fields, field.id)
+		d.oneofs[id].synthetic = field.proto3Optional
 	}
 
-	// NOTE: This may fail to locate types - from the docs:
+	// NOTE: Type names are resolved by resolveFile - from the docs:
 	//
 	//   For message and enum types, this is the name of the type.  if the name
 	//   starts with a '.', it is fully-qualified.  otherwise, c++-like scoping
@@ -143,28 +227,39 @@ func (d *Data) mergeField(f fileID, m *Message, desc *descriptor.FieldDescriptor
 	return field.id
 }
 
-func (d *Data) mergeFile(desc *descriptor.FileDescriptorProto) {
+func (d *Data) mergeFile(desc *descriptor.FileDescriptorProto) error {
 	file := &File{
 		idx:            d.fileCount,
-		id:             fileID(fmt.Sprintf(".%s:%s", *desc.Package, *desc.Name)),
+		id:             fileID(fmt.Sprintf(".%s:%s", desc.GetPackage(), *desc.Name)),
 		data:           d,
 		messages:       make([]messageID, 0, len(desc.MessageType)),
 		enums:          make([]enumID, 0, len(desc.EnumType)),
 		services:       make([]serviceID, 0, len(desc.Service)),
-		sourceCodeInfo: make(map[string]*descriptor.SourceCodeInfo_Location, len(desc.SourceCodeInfo.Location)),
+		extensions:     make([]extensionID, 0, len(desc.Extension)),
+		sourceCodeInfo: make(map[string]*descriptor.SourceCodeInfo_Location, len(desc.GetSourceCodeInfo().GetLocation())),
 		Name:           *desc.Name,
 		Meta:           newFileMetadata(desc.Options),
 		Options:        derefFileOptions(desc.Options),
-		Package:        *desc.Package,
+		Package:        desc.GetPackage(),
 		Generate:       d.filesToGenerate[*desc.Name],
 		Dependencies:   desc.Dependency,
 		Syntax:         toString(desc.Syntax, "proto2"),
+		edition:        fileEdition(desc),
 	}
+	file.Edition = editionName(file.edition)
 	d.fileCount++
 	d.files[file.id] = file
 
+	// Packages define scopes for name resolution, as do their parents
+	if file.Package != "" {
+		parts := strings.Split(file.Package, ".")
+		for i := range parts {
+			d.packages["."+strings.Join(parts[:i+1], ".")] = true
+		}
+	}
+
 	// Build source code index
-	for _, l := range desc.SourceCodeInfo.Location {
+	for _, l := range desc.GetSourceCodeInfo().GetLocation() {
 		pathParts := make([]string, 0, len(l.Path))
 		for _, part := range l.Path {
 			pathParts = append(pathParts, fmt.Sprintf("%d", part))
@@ -174,6 +269,7 @@ func (d *Data) mergeFile(desc *descriptor.FileDescriptorProto) {
 
 	// Package is field 2 in FileDescriptorProto
 	file.Comments = d.comments(file.id, "2")
+	file.Location = d.location(file.id, "")
 
 	for i, dsc := range desc.MessageType {
 		// MessageType is field 4 in FileDescriptorProto
@@ -195,6 +291,15 @@ func (d *Data) mergeFile(desc *descriptor.FileDescriptorProto) {
 		id := d.mergeService(file.id, dsc, p)
 		file.services = append(file.services, id)
 	}
+
+	for i, dsc := range desc.Extension {
+		// Extension is field 7 in FileDescriptorProto
+		p := fmt.Sprintf("7,%d", i)
+		id := d.mergeExtension(file.id, "", dsc, p)
+		file.extensions = append(file.extensions, id)
+	}
+
+	return d.resolveFile(file)
 }
 
 func (d *Data) mergeMessage(f fileID, m messageID, desc *descriptor.DescriptorProto, path string) messageID {
@@ -207,12 +312,16 @@ func (d *Data) mergeMessage(f fileID, m messageID, desc *descriptor.DescriptorPr
 		messages:      make([]messageID, 0, len(desc.NestedType)),
 		enums:         make([]enumID, 0, len(desc.EnumType)),
 		oneofs:        make([]oneofID, 0, len(desc.OneofDecl)),
+		extensions:    make([]extensionID, 0, len(desc.Extension)),
 		Name:          *desc.Name,
 		Meta:          newMessageMetadata(desc.Options),
 		Options:       derefMessageOptions(desc.Options),
 		Comments:      d.comments(f, path),
+		Location:      d.location(f, path),
 		ReservedTags:  make([]descriptor.DescriptorProto_ReservedRange, 0, len(desc.ReservedRange)),
 		ReservedNames: desc.ReservedName,
+
+		ExtensionRanges: make([]ExtensionRange, 0, len(desc.ExtensionRange)),
 	}
 	d.msgCount++
 
@@ -228,6 +337,9 @@ func (d *Data) mergeMessage(f fileID, m messageID, desc *descriptor.DescriptorPr
 	for _, tag := range desc.ReservedRange {
 		message.ReservedTags = append(message.ReservedTags, *tag)
 	}
+	for _, r := range desc.ExtensionRange {
+		message.ExtensionRanges = append(message.ExtensionRanges, ExtensionRange{Start: r.GetStart(), End: r.GetEnd()})
+	}
 
 	for i, dsc := range desc.OneofDecl {
 		// OneofDecl is field 8 in DescriptorProto
@@ -257,11 +369,19 @@ func (d *Data) mergeMessage(f fileID, m messageID, desc *descriptor.DescriptorPr
 		message.enums = append(message.enums, id)
 	}
 
+	for i, dsc := range desc.Extension {
+		// Extension is field 6 in DescriptorProto
+		p := fmt.Sprintf("%s,6,%d", path, i)
+		id := d.mergeExtension(f, message.id, dsc, p)
+		message.extensions = append(message.extensions, id)
+	}
+
 	return message.id
 }
 
 func (d *Data) mergeMethod(f fileID, s serviceID, desc *descriptor.MethodDescriptorProto, path string) methodID {
 	method := &Method{
+		idx:             d.methodCount,
 		id:              methodID(fmt.Sprintf("%s:%s", s, *desc.Name)),
 		data:            d,
 		parent:          s,
@@ -271,11 +391,13 @@ func (d *Data) mergeMethod(f fileID, s serviceID, desc *descriptor.MethodDescrip
 		Meta:            newMethodMetadata(desc.Options),
 		Options:         derefMethodOptions(desc.Options),
 		Comments:        d.comments(f, path),
+		Location:        d.location(f, path),
 		ClientStreaming: derefBool(desc.ClientStreaming),
 		ServerStreaming: derefBool(desc.ServerStreaming),
 	}
+	d.methodCount++
 
-	// NOTE: From the proto comments:
+	// NOTE: Types are checked by resolveFile.  From the proto comments:
 	//
 	//   Input and output type names.  These are resolved in the same way as
 	//   FieldDescriptorProto.type_name, but must refer to a message type.
@@ -286,34 +408,22 @@ func (d *Data) mergeMethod(f fileID, s serviceID, desc *descriptor.MethodDescrip
 	//   message are searched, then within the parent, on up to the root
 	//   namespace).
 	//
-	if _, found := d.messages[method.inputType]; !found {
-		keys := make([]messageID, 0, len(d.messages))
-		for k := range d.messages {
-			keys = append(keys, k)
-		}
-		panic(fmt.Sprintf("No key %s in %v", method.inputType, keys))
-	}
-	if _, found := d.messages[method.outputType]; !found {
-		keys := make([]messageID, 0, len(d.messages))
-		for k := range d.messages {
-			keys = append(keys, k)
-		}
-		panic(fmt.Sprintf("No key %s in %v", method.outputType, keys))
-	}
-
 	d.methods[method.id] = method
 	return method.id
 }
 
 func (d *Data) mergeOneof(f fileID, m messageID, desc *descriptor.OneofDescriptorProto, path string) oneofID {
 	oneof := &Oneof{
+		idx:      d.oneofCount,
 		id:       oneofID(fmt.Sprintf("%s:%s", m, *desc.Name)),
 		data:     d,
 		parent:   m,
 		Name:     *desc.Name,
 		Options:  derefOneofOptions(desc.Options),
 		Comments: d.comments(f, path),
+		Location: d.location(f, path),
 	}
+	d.oneofCount++
 
 	d.oneofs[oneof.id] = oneof
 	return oneof.id
@@ -321,6 +431,7 @@ func (d *Data) mergeOneof(f fileID, m messageID, desc *descriptor.OneofDescripto
 
 func (d *Data) mergeService(f fileID, desc *descriptor.ServiceDescriptorProto, path string) serviceID {
 	service := &Service{
+		idx:      d.serviceCount,
 		id:       serviceID(fmt.Sprintf(".%s.%s", d.files[f].Package, *desc.Name)),
 		data:     d,
 		file:     f,
@@ -329,7 +440,9 @@ func (d *Data) mergeService(f fileID, desc *descriptor.ServiceDescriptorProto, p
 		Meta:     newServiceMetadata(desc.Options),
 		Options:  derefServiceOptions(desc.Options),
 		Comments: d.comments(f, path),
+		Location: d.location(f, path),
 	}
+	d.serviceCount++
 
 	for i, dsc := range desc.Method {
 		// Method is field 2 in ServiceDescriptorProto
@@ -342,13 +455,116 @@ func (d *Data) mergeService(f fileID, desc *descriptor.ServiceDescriptorProto, p
 	return service.id
 }
 
+// resolveFile resolves the type names referred to by a file's fields,
+// extensions and methods to fully-qualified names, and checks they're defined
+func (d *Data) resolveFile(f *File) error {
+	for _, id := range f.messages {
+		if err := d.resolveMessage(f, d.messages[id]); err != nil {
+			return err
+		}
+	}
+	for _, id := range f.extensions {
+		if err := d.resolveExtension(f, d.extensions[id]); err != nil {
+			return err
+		}
+	}
+	scope := packageScope(f.Package)
+	for _, id := range f.services {
+		for _, id := range d.services[id].methods {
+			method := d.methods[id]
+			input, found := d.resolveName(string(method.inputType), scope, d.isMessage)
+			if !found {
+				return &UnresolvedError{File: f.Name, Entity: "input of method " + method.FullName(), Name: string(method.inputType)}
+			}
+			output, found := d.resolveName(string(method.outputType), scope, d.isMessage)
+			if !found {
+				return &UnresolvedError{File: f.Name, Entity: "output of method " + method.FullName(), Name: string(method.outputType)}
+			}
+			method.inputType, method.outputType = messageID(input), messageID(output)
+		}
+	}
+	return nil
+}
+
+func (d *Data) resolveMessage(f *File, m *Message) error {
+	for _, id := range m.fields {
+		field := d.fields[id]
+		if field.typeMessage != "" {
+			name, found := d.resolveName(string(field.typeMessage), string(m.id), d.isMessage)
+			if !found {
+				return &UnresolvedError{File: f.Name, Entity: "field " + field.FullName(), Name: string(field.typeMessage)}
+			}
+			field.typeMessage = messageID(name)
+		}
+		if field.typeEnum != "" {
+			name, found := d.resolveName(string(field.typeEnum), string(m.id), d.isEnum)
+			if !found {
+				return &UnresolvedError{File: f.Name, Entity: "field " + field.FullName(), Name: string(field.typeEnum)}
+			}
+			field.typeEnum = enumID(name)
+		}
+	}
+	for _, id := range m.extensions {
+		if err := d.resolveExtension(f, d.extensions[id]); err != nil {
+			return err
+		}
+	}
+	for _, id := range m.messages {
+		if err := d.resolveMessage(f, d.messages[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Data) resolveExtension(f *File, e *Extension) error {
+	// Extensions are resolved from the scope they're declared in
+	scope := string(e.scope)
+	if scope == "" {
+		scope = packageScope(f.Package)
+	}
+
+	extendee, found := d.resolveName(string(e.extendee), scope, d.isMessage)
+	if !found {
+		return &UnresolvedError{File: f.Name, Entity: "extendee of extension " + e.FullName(), Name: string(e.extendee)}
+	}
+	e.extendee = messageID(extendee)
+	if e.typeMessage != "" {
+		name, found := d.resolveName(string(e.typeMessage), scope, d.isMessage)
+		if !found {
+			return &UnresolvedError{File: f.Name, Entity: "extension " + e.FullName(), Name: string(e.typeMessage)}
+		}
+		e.typeMessage = messageID(name)
+	}
+	if e.typeEnum != "" {
+		name, found := d.resolveName(string(e.typeEnum), scope, d.isEnum)
+		if !found {
+			return &UnresolvedError{File: f.Name, Entity: "extension " + e.FullName(), Name: string(e.typeEnum)}
+		}
+		e.typeEnum = enumID(name)
+	}
+	return nil
+}
+
+// UnresolvedError describes a reference to a type the request doesn't define
+type UnresolvedError struct {
+	File   string // Name of the proto file containing the reference
+	Entity string // Entity containing the reference, ie "field pkg.Msg.name"
+	Name   string // Name of the unresolved type
+}
+
+func (e *UnresolvedError) Error() string {
+	return fmt.Sprintf("%s: %s refers to unresolved type %s", e.File, e.Entity, e.Name)
+}
+
 func (d *Data) comments(f fileID, path string) Comments {
 	location, found := d.files[f].sourceCodeInfo[path]
 	if !found {
-		return Comments{}
+		return Comments{tags: d.commentTags}
 	}
 
 	return Comments{
+		tags:            d.commentTags,
 		Leading:         toString(location.LeadingComments, ""),
 		Trailing:        toString(location.TrailingComments, ""),
 		LeadingDetached: location.LeadingDetachedComments,
@@ -361,6 +577,7 @@ func (d *Data) Enums() EnumSlice {
 	for _, v := range d.enums {
 		vs = append(vs, *v)
 	}
+	sort.Sort(sortedEnumsByIndex(vs))
 	return vs
 }
 
@@ -370,16 +587,31 @@ func (d *Data) EnumValues() EnumValueSlice {
 	for _, v := range d.enumValues {
 		vs = append(vs, *v)
 	}
+	sort.Sort(sortedEnumValuesByIndex(vs))
+	return vs
+}
+
+// Extensions returns a slice of defined extensions
+func (d *Data) Extensions() ExtensionSlice {
+	vs := make([]Extension, 0, len(d.extensions))
+	for _, v := range d.extensions {
+		vs = append(vs, *v)
+	}
+	sort.Sort(sortedExtensionsByIndex(vs))
 	return vs
 }
 
-// Fields returns a slice of defined fields
+// Fields returns a slice of defined fields, excluding the fields of map
+// entries
 func (d *Data) Fields() FieldSlice {
 	vs := make([]Field, 0, len(d.fields))
 	for _, v := range d.fields {
 		if v.Type == descriptor.FieldDescriptorProto_TYPE_GROUP {
 			continue
 		}
+		if v.Parent().IsMapEntry() {
+			continue
+		}
 		vs = append(vs, *v)
 	}
 	sort.Sort(sortedFieldsByIndex(vs))
@@ -396,10 +628,13 @@ func (d *Data) Files() FileSlice {
 	return vs
 }
 
-// Messages returns a slice of defined messages
+// Messages returns a slice of defined messages, excluding map entries
 func (d *Data) Messages() MessageSlice {
 	vs := make([]Message, 0, len(d.messages))
 	for _, v := range d.messages {
+		if v.IsMapEntry() {
+			continue
+		}
 		vs = append(vs, *v)
 	}
 	sort.Sort(sortedMessagesByIndex(vs))
@@ -412,15 +647,33 @@ func (d *Data) Methods() MethodSlice {
 	for _, v := range d.methods {
 		vs = append(vs, *v)
 	}
+	sort.Sort(sortedMethodsByIndex(vs))
 	return vs
 }
 
-// Oneofs returns a slice of defined oneofs
+// Oneofs returns a slice of defined oneofs, excluding synthetic oneofs
 func (d *Data) Oneofs() OneofSlice {
 	vs := make([]Oneof, 0, len(d.oneofs))
 	for _, v := range d.oneofs {
+		if v.synthetic {
+			continue
+		}
 		vs = append(vs, *v)
 	}
+	sort.Sort(sortedOneofsByIndex(vs))
+	return vs
+}
+
+// SyntheticOneofs returns a slice of the synthetic oneofs containing proto3
+// optional fields
+func (d *Data) SyntheticOneofs() OneofSlice {
+	vs := make([]Oneof, 0)
+	for _, v := range d.oneofs {
+		if v.synthetic {
+			vs = append(vs, *v)
+		}
+	}
+	sort.Sort(sortedOneofsByIndex(vs))
 	return vs
 }
 
@@ -430,24 +683,23 @@ func (d *Data) Services() ServiceSlice {
 	for _, v := range d.services {
 		vs = append(vs, *v)
 	}
+	sort.Sort(sortedServicesByIndex(vs))
 	return vs
 }
 
-// PackagesToGenerate returns a slice containing all package names used in files to generate
+// PackagesToGenerate returns a slice containing all package names used in files to generate,
+// in the order files are declared
 func (d *Data) PackagesToGenerate() []string {
 	packageMap := make(map[string]struct{}, len(d.files))
+	packages := make([]string, 0, len(d.files))
 
-	for _, file := range d.files {
-		if file.Generate {
+	for _, file := range d.Files() {
+		if _, found := packageMap[file.Package]; file.Generate && !found {
 			packageMap[file.Package] = struct{}{}
+			packages = append(packages, file.Package)
 		}
 	}
 
-	packages := make([]string, 0, len(packageMap))
-	for pkg := range packageMap {
-		packages = append(packages, pkg)
-	}
-
 	return packages
 }
 
@@ -8,9 +8,9 @@ import (
 
 	"github.com/kerinin/protoc-gen-template/meta"
 	"github.com/kr/pretty"
-	"google.golang.org/protobuf/descriptor"
 	"google.golang.org/protobuf/proto"
-	plugin "google.golang.org/protobuf/protoc-gen-go/plugin"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	plugin "google.golang.org/protobuf/types/pluginpb"
 )
 
 var request plugin.CodeGeneratorRequest
@@ -18,17 +18,65 @@ var request plugin.CodeGeneratorRequest
 func TestMain(m *testing.M) {
 	requestBytes, err := ioutil.ReadFile("testdata/dump.pb")
 	if err != nil {
-		log.Fatal("failed to read testdata/dump.pb: %s", err)
+		log.Fatalf("failed to read testdata/dump.pb: %s", err)
 	}
 
 	err = proto.Unmarshal(requestBytes, &request)
 	if err != nil {
-		log.Fatal("failed to unmarshal testdata/dump.pb: %s", err)
+		log.Fatalf("failed to unmarshal testdata/dump.pb: %s", err)
 	}
 
 	os.Exit(m.Run())
 }
 
+func testData(t *testing.T) *Data {
+	d, err := New(&request)
+	if err != nil {
+		t.Fatalf("reading request: %s", err)
+	}
+	return d
+}
+
+// testFileData returns a Data describing a request generating the given files
+func testFileData(t *testing.T, files ...*descriptor.FileDescriptorProto) *Data {
+	req := &plugin.CodeGeneratorRequest{ProtoFile: files}
+	for _, f := range files {
+		req.FileToGenerate = append(req.FileToGenerate, f.GetName())
+	}
+	d, err := New(req)
+	if err != nil {
+		t.Fatalf("reading request: %s", err)
+	}
+	return d
+}
+
+// testField returns a field as protoc would describe it.  The type name is
+// only set if it isn't empty, as for scalar fields.
+func testField(name string, number int32, label descriptor.FieldDescriptorProto_Label, typ descriptor.FieldDescriptorProto_Type, typeName string) *descriptor.FieldDescriptorProto {
+	f := &descriptor.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    label.Enum(),
+		Type:     typ.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+// fieldsToGenerate returns the fields of the messages in the files to generate
+func fieldsToGenerate(d *Data) FieldSlice {
+	fields := FieldSlice{}
+	for _, f := range d.Fields() {
+		if f.Parent().File().Generate {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
 func stringPointer(s string) *string {
 	return &s
 }
@@ -106,7 +154,7 @@ func TestFiles(t *testing.T) {
 				Syntax:       "proto3",
 			},
 		}
-		actual = New(&request).ToGenerate().Files()
+		actual = testData(t).Files().ToGenerate()
 	)
 
 	for i := 0; i < len(actual); i++ {
@@ -261,7 +309,7 @@ func TestMessages(t *testing.T) {
 				Name:   "OtherMessage",
 			},
 		}
-		actual = New(&request).ToGenerate().Messages()
+		actual = testData(t).Messages().ToGenerate()
 	)
 
 	for i := 0; i < len(actual); i++ {
@@ -290,10 +338,11 @@ func TestFields(t *testing.T) {
 				parent: messageID(".testv2.Message"),
 				Name:   "string_field",
 				Meta: meta.FieldMetadata{
-					Visibility: meta.Visibility_PRIVATE,
-					Generator:  "email",
-					Tags:       []string{"tag1", "tag2"},
-					Extra:      map[string]string{"k": "v"},
+					Visibility:    meta.Visibility_PRIVATE,
+					ExampleString: "example",
+					Generator:     "email",
+					Tags:          []string{"tag1", "tag2"},
+					Extra:         map[string]string{"k": "v"},
 				},
 				Options: descriptor.FieldOptions{
 					Deprecated: boolPointer(true),
@@ -413,10 +462,11 @@ func TestFields(t *testing.T) {
 				parent: messageID(".testv3.Message"),
 				Name:   "string_field",
 				Meta: meta.FieldMetadata{
-					Visibility: meta.Visibility_PRIVATE,
-					Generator:  "email",
-					Tags:       []string{"tag1", "tag2"},
-					Extra:      map[string]string{"k": "v"},
+					Visibility:    meta.Visibility_PRIVATE,
+					ExampleString: "example",
+					Generator:     "email",
+					Tags:          []string{"tag1", "tag2"},
+					Extra:         map[string]string{"k": "v"},
 				},
 				Options: descriptor.FieldOptions{
 					Deprecated: boolPointer(true),
@@ -532,7 +582,7 @@ func TestFields(t *testing.T) {
 				JSONName: "uint32Field",
 			},
 		}
-		actual = New(&request).ToGenerate().Fields()
+		actual = fieldsToGenerate(testData(t))
 	)
 
 	for i := 0; i < len(actual); i++ {
@@ -1,6 +1,9 @@
 package data
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/kerinin/protoc-gen-template/meta"
 	"google.golang.org/protobuf/proto"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
@@ -57,6 +60,7 @@ func (s EnumSlice) NotNested() EnumSlice {
 
 // Enum describes a protobuf enum
 type Enum struct {
+	idx    int
 	id     enumID
 	data   *Data
 	file   fileID
@@ -67,6 +71,7 @@ type Enum struct {
 	Meta     meta.EnumMetadata      // Custom metadata extensions defined for protoc-gen-template
 	Options  descriptor.EnumOptions // Globally-defined enum metadata
 	Comments Comments
+	Location Location // Where the enum is defined
 }
 
 func (e Enum) String() string {
@@ -97,11 +102,21 @@ func (e Enum) IsDeprecated() bool {
 	return e.Options.Deprecated != nil && *e.Options.Deprecated == true
 }
 
+// FullName returns the enum's fully-qualified name, ie "pkg.Msg.Enum"
+func (e Enum) FullName() string {
+	return strings.TrimPrefix(string(e.id), ".")
+}
+
 // IsNested returns true if the enum is embedded in a message
 func (e Enum) IsNested() bool {
 	return e.parent != messageID("")
 }
 
+// Data returns the Data describing the whole code generator request
+func (e Enum) Data() *Data {
+	return e.data
+}
+
 // File returns the containing file
 func (e Enum) File() File {
 	return *e.data.files[e.file]
@@ -112,12 +127,28 @@ func (e Enum) Parent() *Message {
 	return e.data.messages[e.parent]
 }
 
+// Features returns the enum's resolved features, inherited from its file and
+// any enclosing messages
+func (e Enum) Features() Features {
+	if t := e.Parent(); t != nil {
+		return t.Features().merge(e.Options.GetFeatures())
+	}
+	return e.File().Features().merge(e.Options.GetFeatures())
+}
+
+// IsClosed returns true if the enum is closed, so unknown values are treated
+// as unknown fields rather than stored in the field
+func (e Enum) IsClosed() bool {
+	return e.Features().EnumType == descriptor.FeatureSet_CLOSED
+}
+
 // Values returns a slice of the enum's values
 func (e Enum) Values() EnumValueSlice {
 	vs := make([]EnumValue, 0, len(e.values))
 	for _, v := range e.values {
 		vs = append(vs, *e.data.enumValues[v])
 	}
+	sort.Sort(sortedEnumValuesByIndex(vs))
 	return vs
 }
 
@@ -140,3 +171,9 @@ func derefEnumOptions(o *descriptor.EnumOptions) (_ descriptor.EnumOptions) {
 	}
 	return *o
 }
+
+type sortedEnumsByIndex []Enum
+
+func (s sortedEnumsByIndex) Len() int           { return len(s) }
+func (s sortedEnumsByIndex) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortedEnumsByIndex) Less(i, j int) bool { return s[i].idx < s[j].idx }
@@ -1,6 +1,8 @@
 package data
 
 import (
+	"strings"
+
 	"github.com/kerinin/protoc-gen-template/meta"
 	"google.golang.org/protobuf/proto"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
@@ -35,6 +37,7 @@ func (s EnumValueSlice) NotDeprecated() EnumValueSlice {
 
 // EnumValue describes a protobuf enum value
 type EnumValue struct {
+	idx    int
 	id     enumValueID
 	data   *Data
 	parent enumID
@@ -43,6 +46,7 @@ type EnumValue struct {
 	Meta     meta.EnumValueMetadata      // Custom metadata extensions defined for protoc-gen-template
 	Options  descriptor.EnumValueOptions // Globally-defined enum value metadata
 	Comments Comments
+	Location Location // Where the enum value is defined
 	Number   int32
 }
 
@@ -68,6 +72,17 @@ func (e EnumValue) IsDeprecated() bool {
 	return e.Options.Deprecated != nil && *e.Options.Deprecated == true
 }
 
+// FullName returns the value's fully-qualified name.  Enum values are scoped
+// alongside their enum rather than within it, so a value `VALUE` of enum
+// `pkg.Enum` is named "pkg.VALUE".
+func (e EnumValue) FullName() string {
+	scope := e.Parent().FullName()
+	if i := strings.LastIndex(scope, "."); i >= 0 {
+		return scope[:i+1] + e.Name
+	}
+	return e.Name
+}
+
 // Parent returns the enum for which this is a value
 func (e EnumValue) Parent() Enum {
 	return *e.data.enums[e.parent]
@@ -92,3 +107,9 @@ func derefEnumValueOptions(o *descriptor.EnumValueOptions) (_ descriptor.EnumVal
 	}
 	return *o
 }
+
+type sortedEnumValuesByIndex []EnumValue
+
+func (s sortedEnumValuesByIndex) Len() int           { return len(s) }
+func (s sortedEnumValuesByIndex) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortedEnumValuesByIndex) Less(i, j int) bool { return s[i].idx < s[j].idx }
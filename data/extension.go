@@ -0,0 +1,167 @@
+package data
+
+import (
+	"strings"
+
+	"github.com/kerinin/protoc-gen-template/meta"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+type extensionID string
+
+// ExtensionSlice is a slice of extensions
+type ExtensionSlice []Extension
+
+// Visible returns the visible values in the slice
+func (s ExtensionSlice) Visible() ExtensionSlice {
+	outputs := make([]Extension, 0, len(s))
+	for _, f := range s {
+		if f.IsVisible() {
+			outputs = append(outputs, f)
+		}
+	}
+	return outputs
+}
+
+// NotDeprecated returns the non-deprecated values in the slice
+func (s ExtensionSlice) NotDeprecated() ExtensionSlice {
+	outputs := make([]Extension, 0, len(s))
+	for _, f := range s {
+		if !f.IsDeprecated() {
+			outputs = append(outputs, f)
+		}
+	}
+	return outputs
+}
+
+// ToGenerate returns the values in the slice defined in files to be generated
+func (s ExtensionSlice) ToGenerate() ExtensionSlice {
+	outputs := make([]Extension, 0, len(s))
+	for _, f := range s {
+		if f.File().Generate {
+			outputs = append(outputs, f)
+		}
+	}
+	return outputs
+}
+
+// Extension describes a field declared in an `extend` block, extending another
+// message.  Custom options are extensions of the `google.protobuf.*Options`
+// messages.
+type Extension struct {
+	idx         int
+	id          extensionID
+	data        *Data
+	file        fileID
+	scope       messageID // Non-empty for extensions declared in a message
+	extendee    messageID
+	typeMessage messageID
+	typeEnum    enumID
+
+	Name         string
+	Meta         meta.FieldMetadata      // Custom metadata extensions defined for protoc-gen-template
+	Options      descriptor.FieldOptions // Globally-defined field metadata
+	Comments     Comments
+	Location     Location // Where the extension is defined
+	Number       int32
+	Label        descriptor.FieldDescriptorProto_Label
+	Type         descriptor.FieldDescriptorProto_Type
+	DefaultValue string
+	JSONName     string
+}
+
+// ExtensionRange is a range of field numbers reserved for extensions
+type ExtensionRange struct {
+	Start int32 // Inclusive
+	End   int32 // Exclusive
+}
+
+func (e Extension) String() string {
+	return string(e.id)
+}
+
+// FullName returns the extension's fully-qualified name, ie "pkg.Msg.ext"
+func (e Extension) FullName() string {
+	return strings.TrimPrefix(string(e.id), ".")
+}
+
+// IsVisible returns true if the extension's file and any enclosing message are
+// visible, and its visibility metadata is `PUBLIC`
+func (e Extension) IsVisible() bool {
+	if !e.File().IsVisible() {
+		return false
+	}
+	if t := e.Scope(); t != nil && !t.IsVisible() {
+		return false
+	}
+	return e.Meta.Visibility == meta.Visibility_PUBLIC
+}
+
+// IsDeprecated returns true if the extension's file or any enclosing message
+// is deprecated, or if its deprecation option is true
+func (e Extension) IsDeprecated() bool {
+	if e.File().IsDeprecated() {
+		return true
+	}
+	if t := e.Scope(); t != nil && t.IsDeprecated() {
+		return true
+	}
+	return e.Options.Deprecated != nil && *e.Options.Deprecated == true
+}
+
+// Data returns the Data describing the whole code generator request
+func (e Extension) Data() *Data {
+	return e.data
+}
+
+// File returns the file declaring the extension
+func (e Extension) File() File {
+	return *e.data.files[e.file]
+}
+
+// Scope returns the message the extension is declared in, else nil for
+// extensions declared at the top level of a file
+func (e Extension) Scope() *Message {
+	return e.data.messages[e.scope]
+}
+
+// IsNested returns true if the extension is declared in a message
+func (e Extension) IsNested() bool {
+	return e.scope != messageID("")
+}
+
+// Extendee returns the message being extended
+func (e Extension) Extendee() *Message {
+	return e.data.messages[e.extendee]
+}
+
+// TypeMessage returns the message type if the extension is message-typed, else
+// nil
+func (e Extension) TypeMessage() *Message {
+	return e.data.messages[e.typeMessage]
+}
+
+// TypeEnum returns the enum type if the extension is enum-typed, else nil
+func (e Extension) TypeEnum() *Enum {
+	return e.data.enums[e.typeEnum]
+}
+
+// IsRepeated is true if the extension's label is 'REPEATED'
+func (e Extension) IsRepeated() bool {
+	return e.Label == descriptor.FieldDescriptorProto_LABEL_REPEATED
+}
+
+// TypeNameString returns a prettified string descripton of the extension's
+// type, as Field.TypeNameString does
+func (e Extension) TypeNameString() string {
+	if e.IsRepeated() {
+		return "[]" + typeName(e.Type, e.typeMessage, e.typeEnum)
+	}
+	return typeName(e.Type, e.typeMessage, e.typeEnum)
+}
+
+type sortedExtensionsByIndex []Extension
+
+func (s sortedExtensionsByIndex) Len() int           { return len(s) }
+func (s sortedExtensionsByIndex) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortedExtensionsByIndex) Less(i, j int) bool { return s[i].idx < s[j].idx }
@@ -0,0 +1,82 @@
+package data
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestExtensions(t *testing.T) {
+	var (
+		optional = descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+		repeated = descriptor.FieldDescriptorProto_LABEL_REPEATED
+		note     = testField("note", 100, optional, descriptor.FieldDescriptorProto_TYPE_STRING, "")
+		holders  = testField("holders", 101, repeated, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".ext.Holder")
+	)
+	note.Extendee = proto.String(".base.Base")
+	holders.Extendee = proto.String(".base.Base")
+
+	d := testFileData(t,
+		&descriptor.FileDescriptorProto{
+			Name:    proto.String("base.proto"),
+			Package: proto.String("base"),
+			MessageType: []*descriptor.DescriptorProto{{
+				Name:           proto.String("Base"),
+				ExtensionRange: []*descriptor.DescriptorProto_ExtensionRange{{Start: proto.Int32(100), End: proto.Int32(200)}},
+			}},
+		},
+		&descriptor.FileDescriptorProto{
+			Name:       proto.String("ext.proto"),
+			Package:    proto.String("ext"),
+			Dependency: []string{"base.proto"},
+			Extension:  []*descriptor.FieldDescriptorProto{note},
+			MessageType: []*descriptor.DescriptorProto{{
+				Name:      proto.String("Holder"),
+				Extension: []*descriptor.FieldDescriptorProto{holders},
+			}},
+		},
+	)
+
+	extensions := d.Extensions()
+	if len(extensions) != 2 {
+		t.Fatalf("expected 2 extensions, got %d", len(extensions))
+	}
+	for i, test := range []struct {
+		fullName, typeName, scope string
+		number                    int32
+	}{
+		{"ext.Holder.holders", "[]ext.Holder", "ext.Holder", 101},
+		{"ext.note", "string", "", 100},
+	} {
+		e := extensions[i]
+		if e.FullName() != test.fullName || e.Number != test.number || e.TypeNameString() != test.typeName {
+			t.Errorf("%s: expected %s %d %s, got %s %d %s", test.fullName,
+				test.fullName, test.number, test.typeName,
+				e.FullName(), e.Number, e.TypeNameString())
+		}
+		if extendee := e.Extendee(); extendee == nil || extendee.FullName() != "base.Base" {
+			t.Errorf("%s: expected extendee base.Base, got %v", test.fullName, extendee)
+		}
+		if e.IsNested() != (test.scope != "") {
+			t.Errorf("%s: expected IsNested %t", test.fullName, test.scope != "")
+		}
+		if scope := e.Scope(); test.scope != "" && (scope == nil || scope.FullName() != test.scope) {
+			t.Errorf("%s: expected scope %s, got %v", test.fullName, test.scope, scope)
+		}
+	}
+
+	if e := d.File("ext.proto").Extensions(); len(e) != 1 || e[0].Name != "note" {
+		t.Errorf("expected the file to declare note, got %v", e)
+	}
+	if e := d.Message("ext.Holder").Extensions(); len(e) != 1 || e[0].Name != "holders" {
+		t.Errorf("expected Holder to declare holders, got %v", e)
+	}
+	base := d.Message("base.Base")
+	if e := base.ExtendedBy(); len(e) != 2 {
+		t.Errorf("expected Base to be extended twice, got %v", e)
+	}
+	if r := base.ExtensionRanges; len(r) != 1 || r[0] != (ExtensionRange{Start: 100, End: 200}) {
+		t.Errorf("expected extension range 100 to 200, got %v", r)
+	}
+}
@@ -0,0 +1,100 @@
+package data
+
+import (
+	"strings"
+
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Features are the resolved values of the features controlling how a file,
+// message, field or enum behaves.  Features are inherited from the file to
+// nested messages, and from messages to their fields and enums.  Files using
+// proto2 or proto3 syntax resolve features from their syntax and legacy
+// options.
+type Features struct {
+	FieldPresence         descriptor.FeatureSet_FieldPresence
+	EnumType              descriptor.FeatureSet_EnumType
+	RepeatedFieldEncoding descriptor.FeatureSet_RepeatedFieldEncoding
+	UTF8Validation        descriptor.FeatureSet_Utf8Validation
+	MessageEncoding       descriptor.FeatureSet_MessageEncoding
+	JSONFormat            descriptor.FeatureSet_JsonFormat
+}
+
+// editionDefaults are the default features of each supported edition
+var editionDefaults = map[descriptor.Edition]Features{
+	descriptor.Edition_EDITION_PROTO2: {
+		FieldPresence:         descriptor.FeatureSet_EXPLICIT,
+		EnumType:              descriptor.FeatureSet_CLOSED,
+		RepeatedFieldEncoding: descriptor.FeatureSet_EXPANDED,
+		UTF8Validation:        descriptor.FeatureSet_NONE,
+		MessageEncoding:       descriptor.FeatureSet_LENGTH_PREFIXED,
+		JSONFormat:            descriptor.FeatureSet_LEGACY_BEST_EFFORT,
+	},
+	descriptor.Edition_EDITION_PROTO3: {
+		FieldPresence:         descriptor.FeatureSet_IMPLICIT,
+		EnumType:              descriptor.FeatureSet_OPEN,
+		RepeatedFieldEncoding: descriptor.FeatureSet_PACKED,
+		UTF8Validation:        descriptor.FeatureSet_VERIFY,
+		MessageEncoding:       descriptor.FeatureSet_LENGTH_PREFIXED,
+		JSONFormat:            descriptor.FeatureSet_ALLOW,
+	},
+	descriptor.Edition_EDITION_2023: {
+		FieldPresence:         descriptor.FeatureSet_EXPLICIT,
+		EnumType:              descriptor.FeatureSet_OPEN,
+		RepeatedFieldEncoding: descriptor.FeatureSet_PACKED,
+		UTF8Validation:        descriptor.FeatureSet_VERIFY,
+		MessageEncoding:       descriptor.FeatureSet_LENGTH_PREFIXED,
+		JSONFormat:            descriptor.FeatureSet_ALLOW,
+	},
+}
+
+// merge returns the features with any values set in fs overriding them
+func (f Features) merge(fs *descriptor.FeatureSet) Features {
+	if fs == nil {
+		return f
+	}
+	if fs.FieldPresence != nil {
+		f.FieldPresence = fs.GetFieldPresence()
+	}
+	if fs.EnumType != nil {
+		f.EnumType = fs.GetEnumType()
+	}
+	if fs.RepeatedFieldEncoding != nil {
+		f.RepeatedFieldEncoding = fs.GetRepeatedFieldEncoding()
+	}
+	if fs.Utf8Validation != nil {
+		f.UTF8Validation = fs.GetUtf8Validation()
+	}
+	if fs.MessageEncoding != nil {
+		f.MessageEncoding = fs.GetMessageEncoding()
+	}
+	if fs.JsonFormat != nil {
+		f.JSONFormat = fs.GetJsonFormat()
+	}
+	return f
+}
+
+// fileEdition returns the edition of a file, translating proto2 and proto3
+// syntax to their equivalent editions
+func fileEdition(desc *descriptor.FileDescriptorProto) descriptor.Edition {
+	switch desc.GetSyntax() {
+	case "", "proto2":
+		return descriptor.Edition_EDITION_PROTO2
+	case "proto3":
+		return descriptor.Edition_EDITION_PROTO3
+	default:
+		return desc.GetEdition()
+	}
+}
+
+// editionName returns a short name for an edition, ie "proto3" or "2023"
+func editionName(e descriptor.Edition) string {
+	switch e {
+	case descriptor.Edition_EDITION_PROTO2:
+		return "proto2"
+	case descriptor.Edition_EDITION_PROTO3:
+		return "proto3"
+	default:
+		return strings.TrimPrefix(e.String(), "EDITION_")
+	}
+}
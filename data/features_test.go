@@ -0,0 +1,104 @@
+package data
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestFeatures(t *testing.T) {
+	var (
+		optional    = descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+		str         = descriptor.FieldDescriptorProto_TYPE_STRING
+		limitField  = testField("limit", 2, optional, str, "")
+		choiceField = testField("choice", 3, optional, str, "")
+	)
+	limitField.Options = &descriptor.FieldOptions{Features: &descriptor.FeatureSet{
+		FieldPresence: descriptor.FeatureSet_EXPLICIT.Enum(),
+	}}
+	choiceField.OneofIndex = proto.Int32(0)
+
+	d := testFileData(t,
+		&descriptor.FileDescriptorProto{
+			Name:    proto.String("editions.proto"),
+			Package: proto.String("editions"),
+			Syntax:  proto.String("editions"),
+			Edition: descriptor.Edition_EDITION_2023.Enum(),
+			Options: &descriptor.FileOptions{Features: &descriptor.FeatureSet{
+				FieldPresence: descriptor.FeatureSet_IMPLICIT.Enum(),
+			}},
+			MessageType: []*descriptor.DescriptorProto{{
+				Name: proto.String("Config"),
+				Options: &descriptor.MessageOptions{Features: &descriptor.FeatureSet{
+					EnumType: descriptor.FeatureSet_CLOSED.Enum(),
+				}},
+				Field: []*descriptor.FieldDescriptorProto{
+					testField("count", 1, optional, str, ""),
+					limitField,
+					choiceField,
+				},
+				OneofDecl: []*descriptor.OneofDescriptorProto{{
+					Name: proto.String("kind"),
+					Options: &descriptor.OneofOptions{Features: &descriptor.FeatureSet{
+						Utf8Validation: descriptor.FeatureSet_NONE.Enum(),
+					}},
+				}},
+				NestedType: []*descriptor.DescriptorProto{{
+					Name:  proto.String("Inner"),
+					Field: []*descriptor.FieldDescriptorProto{testField("name", 1, optional, str, "")},
+				}},
+				EnumType: []*descriptor.EnumDescriptorProto{{
+					Name:  proto.String("Mode"),
+					Value: []*descriptor.EnumValueDescriptorProto{{Name: proto.String("MODE_UNSPECIFIED"), Number: proto.Int32(0)}},
+				}},
+			}},
+			EnumType: []*descriptor.EnumDescriptorProto{{
+				Name:  proto.String("Level"),
+				Value: []*descriptor.EnumValueDescriptorProto{{Name: proto.String("LEVEL_UNSPECIFIED"), Number: proto.Int32(0)}},
+			}},
+		},
+		&descriptor.FileDescriptorProto{
+			Name:        proto.String("proto3.proto"),
+			Package:     proto.String("proto3"),
+			Syntax:      proto.String("proto3"),
+			MessageType: []*descriptor.DescriptorProto{{Name: proto.String("Message")}},
+		},
+	)
+
+	fields := map[string]Field{}
+	for _, f := range d.Fields() {
+		fields[f.FullName()] = f
+	}
+
+	edition2023 := editionDefaults[descriptor.Edition_EDITION_2023]
+	file := edition2023
+	file.FieldPresence = descriptor.FeatureSet_IMPLICIT
+	message := file
+	message.EnumType = descriptor.FeatureSet_CLOSED
+	limit := message
+	limit.FieldPresence = descriptor.FeatureSet_EXPLICIT
+	choiceFeatures := message
+	choiceFeatures.UTF8Validation = descriptor.FeatureSet_NONE
+
+	for _, test := range []struct {
+		name     string
+		expected Features
+		actual   Features
+	}{
+		{"file", file, d.File("editions.proto").Features()},
+		{"message", message, d.Message(".editions.Config").Features()},
+		{"nested message", message, d.Message(".editions.Config.Inner").Features()},
+		{"field", message, fields["editions.Config.count"].Features()},
+		{"overriding field", limit, fields["editions.Config.limit"].Features()},
+		{"oneof field", choiceFeatures, fields["editions.Config.choice"].Features()},
+		{"nested field", message, fields["editions.Config.Inner.name"].Features()},
+		{"nested enum", message, d.Enum(".editions.Config.Mode").Features()},
+		{"enum", file, d.Enum(".editions.Level").Features()},
+		{"proto3 message", editionDefaults[descriptor.Edition_EDITION_PROTO3], d.Message(".proto3.Message").Features()},
+	} {
+		if test.actual != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, test.actual)
+		}
+	}
+}
@@ -51,14 +51,17 @@ type Field struct {
 	id          fieldID
 	data        *Data
 	parent      messageID
-	oneof       oneofID // Non-empty for oneof fields
+	oneof       oneofID // Non-empty for oneof fields, including synthetic oneofs
 	typeMessage messageID
 	typeEnum    enumID
 
+	proto3Optional bool
+
 	Name     string
 	Meta     meta.FieldMetadata      // Custom metadata extensions defined for protoc-gen-template
 	Options  descriptor.FieldOptions // Globally-defined field metadata
 	Comments Comments
+	Location Location // Where the field is defined
 	Number   int32
 	Label    descriptor.FieldDescriptorProto_Label
 	Type     descriptor.FieldDescriptorProto_Type
@@ -79,6 +82,11 @@ func (f Field) String() string {
 	return string(f.id)
 }
 
+// FullName returns the field's fully-qualified name, ie "pkg.Msg.field"
+func (f Field) FullName() string {
+	return f.Parent().FullName() + "." + f.Name
+}
+
 // IsVisible returns true if the field's parent and type is visible and its
 // visibility metadata `PUBLIC`
 func (f Field) IsVisible() bool {
@@ -114,8 +122,12 @@ func (f Field) Parent() Message {
 	return *f.data.messages[f.parent]
 }
 
-// Oneof returns the oneof this field is a member of, else nil
+// Oneof returns the oneof this field is a member of, else nil.  The synthetic
+// oneofs of proto3 optional fields aren't returned.
 func (f Field) Oneof() *Oneof {
+	if f.proto3Optional {
+		return nil
+	}
 	return f.data.oneofs[f.oneof]
 }
 
@@ -124,6 +136,82 @@ func (f Field) IsOneof() bool {
 	return f.Oneof() != nil
 }
 
+// IsProto3Optional returns true if the field is declared `optional` in a
+// proto3 file
+func (f Field) IsProto3Optional() bool {
+	return f.proto3Optional
+}
+
+// HasPresence returns true if the field tracks whether it has been set,
+// distinguishing an unset field from one set to its default value.  Repeated
+// fields never have presence, and message-typed fields and oneof members
+// always do.  Other fields have presence unless their resolved field presence
+// is `IMPLICIT`, as it is for proto3 fields not declared `optional`.
+func (f Field) HasPresence() bool {
+	switch {
+	case f.IsRepeated():
+		return false
+	case f.IsTypeMessage(), f.IsTypeGroup(), f.oneof != oneofID(""):
+		return true
+	default:
+		return f.Features().FieldPresence != descriptor.FeatureSet_IMPLICIT
+	}
+}
+
+// IsPacked returns true if the field is a repeated scalar using the packed
+// encoding
+func (f Field) IsPacked() bool {
+	if !f.IsRepeated() {
+		return false
+	}
+	switch f.Type {
+	case descriptor.FieldDescriptorProto_TYPE_STRING,
+		descriptor.FieldDescriptorProto_TYPE_BYTES,
+		descriptor.FieldDescriptorProto_TYPE_MESSAGE,
+		descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return false
+	}
+	return f.Features().RepeatedFieldEncoding == descriptor.FeatureSet_PACKED
+}
+
+// ValidatesUTF8 returns true if the field is string-typed and its content must
+// be valid UTF-8
+func (f Field) ValidatesUTF8() bool {
+	return f.IsTypeString() && f.Features().UTF8Validation == descriptor.FeatureSet_VERIFY
+}
+
+// Features returns the field's resolved features, inherited from its message
+// and any enclosing oneof.  Fields in proto2 and proto3 files resolve their
+// features from the `required` label, `optional` keyword, `packed` option and
+// group type.
+func (f Field) Features() Features {
+	features := f.Parent().Features()
+	if o := f.Oneof(); o != nil {
+		features = features.merge(o.Options.GetFeatures())
+	}
+	features = features.merge(f.Options.GetFeatures())
+
+	switch f.Parent().File().edition {
+	case descriptor.Edition_EDITION_PROTO2, descriptor.Edition_EDITION_PROTO3:
+		if f.Label == descriptor.FieldDescriptorProto_LABEL_REQUIRED {
+			features.FieldPresence = descriptor.FeatureSet_LEGACY_REQUIRED
+		}
+		if f.proto3Optional {
+			features.FieldPresence = descriptor.FeatureSet_EXPLICIT
+		}
+		if f.Options.Packed != nil {
+			features.RepeatedFieldEncoding = descriptor.FeatureSet_EXPANDED
+			if f.Options.GetPacked() {
+				features.RepeatedFieldEncoding = descriptor.FeatureSet_PACKED
+			}
+		}
+		if f.IsTypeGroup() {
+			features.MessageEncoding = descriptor.FeatureSet_DELIMITED
+		}
+	}
+	return features
+}
+
 // TypeMessage returns the message type if the field is message-typed, else nil
 func (f Field) TypeMessage() *Message {
 	return f.data.messages[f.typeMessage]
@@ -134,11 +222,44 @@ func (f Field) TypeEnum() *Enum {
 	return f.data.enums[f.typeEnum]
 }
 
-// IsRepeated is true if the field's label is 'REPEATED'
+// IsRepeated is true if the field's label is 'REPEATED'.  Map fields are
+// repeated.
 func (f Field) IsRepeated() bool {
 	return f.Label == descriptor.FieldDescriptorProto_LABEL_REPEATED
 }
 
+// IsMap is true if the field is a map, represented as a repeated field of a
+// synthetic map entry message
+func (f Field) IsMap() bool {
+	if !f.IsRepeated() {
+		return false
+	}
+	t := f.TypeMessage()
+	return t != nil && t.IsMapEntry()
+}
+
+// MapKey returns the key field of a map field's entry, else nil
+func (f Field) MapKey() *Field {
+	return f.mapEntryField(1)
+}
+
+// MapValue returns the value field of a map field's entry, else nil
+func (f Field) MapValue() *Field {
+	return f.mapEntryField(2)
+}
+
+func (f Field) mapEntryField(number int32) *Field {
+	if !f.IsMap() {
+		return nil
+	}
+	for _, v := range f.TypeMessage().fields {
+		if f.data.fields[v].Number == number {
+			return f.data.fields[v]
+		}
+	}
+	return nil
+}
+
 // IsTypeDouble is true if the field's type is 'double'
 func (f Field) IsTypeDouble() bool {
 	return f.Type == descriptor.FieldDescriptorProto_TYPE_DOUBLE
@@ -238,8 +359,13 @@ func (f Field) IsTypeMessage() bool {
 //   {Type: "TYPE_BYTES", TypeName: nil, Label: REPEATED}			-> "bytes"
 //   {Type: "TYPE_MESSAGE", TypeName: ".pkg.Msg, Label: OPTIONAL}	-> "pkg.Msg"
 //   {Type: "TYPE_ENUM", TypeName: ".pkg.Enm, Label: OPTIONAL}		-> "pkg.Enm"
+//   {Type: "TYPE_MESSAGE", TypeName: ".pkg.Msg.FooEntry, Label: REPEATED}	-> "map<string,pkg.Msg>"
 //
 func (f Field) TypeNameString() string {
+	if f.IsMap() {
+		return fmt.Sprintf("map<%s,%s>", f.MapKey().typeName(), f.MapValue().typeName())
+	}
+
 	switch f.Label {
 	case descriptor.FieldDescriptorProto_LABEL_REPEATED:
 		return fmt.Sprintf("[]" + f.typeName())
@@ -252,14 +378,19 @@ func (f Field) TypeNameString() string {
 }
 
 func (f Field) typeName() string {
-	if f.typeMessage != messageID("") {
-		return strings.TrimPrefix(string(f.typeMessage), ".")
+	return typeName(f.Type, f.typeMessage, f.typeEnum)
+}
+
+// typeName returns the name of a message or enum type, or of a scalar type
+func typeName(t descriptor.FieldDescriptorProto_Type, typeMessage messageID, typeEnum enumID) string {
+	if typeMessage != messageID("") {
+		return strings.TrimPrefix(string(typeMessage), ".")
 	}
-	if f.typeEnum != enumID("") {
-		return strings.TrimPrefix(string(f.typeEnum), ".")
+	if typeEnum != enumID("") {
+		return strings.TrimPrefix(string(typeEnum), ".")
 	}
 
-	switch f.Type {
+	switch t {
 	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
 		return "double"
 	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
@@ -297,7 +428,7 @@ func (f Field) typeName() string {
 	case descriptor.FieldDescriptorProto_TYPE_SINT64:
 		return "sint64"
 	default:
-		return f.Type.String()
+		return t.String()
 	}
 }
 
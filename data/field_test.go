@@ -0,0 +1,182 @@
+package data
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// testMapEntry returns the synthetic entry message protoc describes a map
+// field with
+func testMapEntry(name string, key descriptor.FieldDescriptorProto_Type, value descriptor.FieldDescriptorProto_Type, valueTypeName string) *descriptor.DescriptorProto {
+	optional := descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+	return &descriptor.DescriptorProto{
+		Name:    proto.String(name),
+		Options: &descriptor.MessageOptions{MapEntry: proto.Bool(true)},
+		Field: []*descriptor.FieldDescriptorProto{
+			testField("key", 1, optional, key, ""),
+			testField("value", 2, optional, value, valueTypeName),
+		},
+	}
+}
+
+func TestFieldMaps(t *testing.T) {
+	repeated := descriptor.FieldDescriptorProto_LABEL_REPEATED
+	d := testFileData(t, &descriptor.FileDescriptorProto{
+		Name:    proto.String("maps.proto"),
+		Package: proto.String("maps"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptor.DescriptorProto{{
+			Name: proto.String("Inventory"),
+			Field: []*descriptor.FieldDescriptorProto{
+				testField("items", 1, repeated, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".maps.Inventory.ItemsEntry"),
+				testField("tags", 2, repeated, descriptor.FieldDescriptorProto_TYPE_STRING, ""),
+				testField("levels", 3, repeated, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".maps.Inventory.LevelsEntry"),
+			},
+			NestedType: []*descriptor.DescriptorProto{
+				testMapEntry("ItemsEntry", descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".maps.Inventory.Item"),
+				testMapEntry("LevelsEntry", descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_ENUM, ".maps.Level"),
+				{Name: proto.String("Item")},
+			},
+		}},
+		EnumType: []*descriptor.EnumDescriptorProto{{
+			Name:  proto.String("Level"),
+			Value: []*descriptor.EnumValueDescriptorProto{{Name: proto.String("LEVEL_UNSPECIFIED"), Number: proto.Int32(0)}},
+		}},
+	})
+
+	fields := map[string]Field{}
+	for _, f := range d.Fields() {
+		fields[f.Name] = f
+	}
+	if len(fields) != 3 {
+		t.Errorf("expected map entry fields to be omitted, got %d fields", len(fields))
+	}
+
+	for _, test := range []struct {
+		name, typeName string
+		isMap          bool
+		key, value     string
+	}{
+		{"items", "map<string,maps.Inventory.Item>", true, "string", "maps.Inventory.Item"},
+		{"tags", "[]string", false, "", ""},
+		{"levels", "map<int32,maps.Level>", true, "int32", "maps.Level"},
+	} {
+		f := fields[test.name]
+		if f.IsMap() != test.isMap {
+			t.Errorf("%s: expected IsMap %t", test.name, test.isMap)
+		}
+		if actual := f.TypeNameString(); actual != test.typeName {
+			t.Errorf("%s: expected type name %q, got %q", test.name, test.typeName, actual)
+		}
+		if !test.isMap {
+			if f.MapKey() != nil || f.MapValue() != nil {
+				t.Errorf("%s: expected no map key or value", test.name)
+			}
+			continue
+		}
+		if key := f.MapKey(); key == nil || key.Name != "key" || key.TypeNameString() != test.key {
+			t.Errorf("%s: expected %s key, got %v", test.name, test.key, key)
+		}
+		if value := f.MapValue(); value == nil || value.Name != "value" || value.TypeNameString() != test.value {
+			t.Errorf("%s: expected %s value, got %v", test.name, test.value, value)
+		}
+	}
+
+	var names []string
+	for _, m := range d.Messages() {
+		names = append(names, m.FullName())
+	}
+	if len(names) != 2 || names[0] != "maps.Inventory" || names[1] != "maps.Inventory.Item" {
+		t.Errorf("expected map entries to be omitted from messages, got %v", names)
+	}
+	if nested := d.Message("maps.Inventory").Messages(); len(nested) != 1 || nested[0].Name != "Item" {
+		t.Errorf("expected map entries to be omitted from nested messages, got %v", nested)
+	}
+	if entry := fields["items"].TypeMessage(); entry == nil || !entry.IsMapEntry() {
+		t.Errorf("expected items to be typed by a map entry, got %v", entry)
+	}
+}
+
+func TestFieldPresence(t *testing.T) {
+	var (
+		optional  = descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+		repeated  = descriptor.FieldDescriptorProto_LABEL_REPEATED
+		required  = descriptor.FieldDescriptorProto_LABEL_REQUIRED
+		int32Type = descriptor.FieldDescriptorProto_TYPE_INT32
+		name      = testField("name", 3, optional, descriptor.FieldDescriptorProto_TYPE_STRING, "")
+		count     = testField("count", 4, optional, int32Type, "")
+	)
+	name.OneofIndex = proto.Int32(0)
+	count.OneofIndex = proto.Int32(1)
+	count.Proto3Optional = proto.Bool(true)
+
+	d := testFileData(t,
+		&descriptor.FileDescriptorProto{
+			Name:    proto.String("proto3.proto"),
+			Package: proto.String("proto3"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptor.DescriptorProto{{
+				Name: proto.String("Message"),
+				Field: []*descriptor.FieldDescriptorProto{
+					testField("child", 1, optional, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".proto3.Message"),
+					testField("ids", 2, repeated, int32Type, ""),
+					name,
+					count,
+					testField("total", 5, optional, int32Type, ""),
+				},
+				OneofDecl: []*descriptor.OneofDescriptorProto{
+					{Name: proto.String("label")},
+					{Name: proto.String("_count")},
+				},
+			}},
+		},
+		&descriptor.FileDescriptorProto{
+			Name:    proto.String("proto2.proto"),
+			Package: proto.String("proto2"),
+			MessageType: []*descriptor.DescriptorProto{{
+				Name: proto.String("Message"),
+				Field: []*descriptor.FieldDescriptorProto{
+					testField("total", 1, optional, int32Type, ""),
+					testField("id", 2, required, int32Type, ""),
+				},
+			}},
+		},
+	)
+
+	fields := map[string]Field{}
+	for _, f := range d.Fields() {
+		fields[f.FullName()] = f
+	}
+	for _, test := range []struct {
+		name                               string
+		hasPresence, proto3Optional, oneof bool
+	}{
+		{"proto3.Message.child", true, false, false},
+		{"proto3.Message.ids", false, false, false},
+		{"proto3.Message.name", true, false, true},
+		{"proto3.Message.count", true, true, false},
+		{"proto3.Message.total", false, false, false},
+		{"proto2.Message.total", true, false, false},
+		{"proto2.Message.id", true, false, false},
+	} {
+		f := fields[test.name]
+		if f.HasPresence() != test.hasPresence || f.IsProto3Optional() != test.proto3Optional || f.IsOneof() != test.oneof {
+			t.Errorf("%s: expected presence %t, proto3 optional %t, oneof %t, got %t, %t, %t", test.name,
+				test.hasPresence, test.proto3Optional, test.oneof,
+				f.HasPresence(), f.IsProto3Optional(), f.IsOneof())
+		}
+	}
+
+	m := d.Message("proto3.Message")
+	if oneofs := m.Oneofs(); len(oneofs) != 1 || oneofs[0].Name != "label" || oneofs[0].IsSynthetic() {
+		t.Errorf("expected only the declared oneof, got %v", oneofs)
+	}
+	if oneofs := m.SyntheticOneofs(); len(oneofs) != 1 || oneofs[0].Name != "_count" || !oneofs[0].IsSynthetic() {
+		t.Errorf("expected the synthetic oneof, got %v", oneofs)
+	}
+	if len(d.Oneofs()) != 1 || len(d.SyntheticOneofs()) != 1 {
+		t.Errorf("expected one declared and one synthetic oneof, got %v and %v", d.Oneofs(), d.SyntheticOneofs())
+	}
+}
@@ -56,16 +56,20 @@ type File struct {
 	messages       []messageID
 	enums          []enumID
 	services       []serviceID
+	extensions     []extensionID
 	sourceCodeInfo map[string]*descriptor.SourceCodeInfo_Location
+	edition        descriptor.Edition
 
 	Package      string
 	Name         string
 	Comments     Comments
+	Location     Location               // Where the file is defined
 	Meta         meta.FileMetadata      // Custom metadata extensions defined for protoc-gen-template
 	Options      descriptor.FileOptions // Globally-defined file metadata
 	Generate     bool                   // Generate is true if the file is included in FileToGenerate
 	Dependencies []string               // Dependencies lists the names of files imported by this file.
-	Syntax       string                 // Syntax is of the proto file - proto2/proto3
+	Syntax       string                 // Syntax is of the proto file - proto2/proto3/editions
+	Edition      string                 // Edition is the file's edition, ie "2023", or its syntax for proto2/proto3 files
 }
 
 func (f File) String() string {
@@ -118,6 +122,21 @@ func (f File) GoPackageImport() string {
 	return strings.Split(*f.Options.GoPackage, `;`)[0]
 }
 
+// Features returns the file's resolved features, which are inherited by its
+// messages and enums
+func (f File) Features() Features {
+	defaults, found := editionDefaults[f.edition]
+	if !found {
+		defaults = editionDefaults[descriptor.Edition_EDITION_2023]
+	}
+	return defaults.merge(f.Options.GetFeatures())
+}
+
+// Data returns the Data describing the whole code generator request
+func (f File) Data() *Data {
+	return f.data
+}
+
 // Messages returns a slice of the file's messages
 func (f File) Messages() MessageSlice {
 	vs := make([]Message, 0, len(f.messages))
@@ -134,6 +153,7 @@ func (f File) Enums() EnumSlice {
 	for _, v := range f.enums {
 		vs = append(vs, *f.data.enums[v])
 	}
+	sort.Sort(sortedEnumsByIndex(vs))
 	return vs
 }
 
@@ -143,6 +163,18 @@ func (f File) Services() ServiceSlice {
 	for _, v := range f.services {
 		vs = append(vs, *f.data.services[v])
 	}
+	sort.Sort(sortedServicesByIndex(vs))
+	return vs
+}
+
+// Extensions returns a slice of the extensions declared at the top level of
+// the file
+func (f File) Extensions() ExtensionSlice {
+	vs := make([]Extension, 0, len(f.extensions))
+	for _, v := range f.extensions {
+		vs = append(vs, *f.data.extensions[v])
+	}
+	sort.Sort(sortedExtensionsByIndex(vs))
 	return vs
 }
 
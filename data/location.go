@@ -0,0 +1,47 @@
+package data
+
+import (
+	"fmt"
+)
+
+// Location describes where an entity is defined in its proto file.  Lines and
+// columns are 1-based, and the end column is exclusive.  Lines are zero if the
+// request didn't include source code info.
+type Location struct {
+	File        string
+	StartLine   int
+	StartColumn int
+	EndLine     int
+	EndColumn   int
+}
+
+// String returns the location as "file:line", or just the file if the line
+// isn't known
+func (l Location) String() string {
+	if l.StartLine == 0 {
+		return l.File
+	}
+	return fmt.Sprintf("%s:%d", l.File, l.StartLine)
+}
+
+func (d *Data) location(f fileID, path string) Location {
+	file := d.files[f]
+	location := Location{File: file.Name}
+
+	l, found := file.sourceCodeInfo[path]
+	if !found {
+		return location
+	}
+
+	// NOTE: Spans have three elements if the entity starts and ends on the same
+	// line: start line, start column, end column.  Values are 0-based.
+	switch span := l.Span; len(span) {
+	case 3:
+		location.StartLine, location.StartColumn = int(span[0])+1, int(span[1])+1
+		location.EndLine, location.EndColumn = int(span[0])+1, int(span[2])+1
+	case 4:
+		location.StartLine, location.StartColumn = int(span[0])+1, int(span[1])+1
+		location.EndLine, location.EndColumn = int(span[2])+1, int(span[3])+1
+	}
+	return location
+}
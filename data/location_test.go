@@ -0,0 +1,57 @@
+package data
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestLocation(t *testing.T) {
+	optional := descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+	d := testFileData(t, &descriptor.FileDescriptorProto{
+		Name:    proto.String("loc.proto"),
+		Package: proto.String("loc"),
+		MessageType: []*descriptor.DescriptorProto{{
+			Name: proto.String("Msg"),
+			Field: []*descriptor.FieldDescriptorProto{
+				testField("name", 1, optional, descriptor.FieldDescriptorProto_TYPE_STRING, ""),
+				testField("age", 2, optional, descriptor.FieldDescriptorProto_TYPE_INT32, ""),
+			},
+		}},
+		SourceCodeInfo: &descriptor.SourceCodeInfo{
+			Location: []*descriptor.SourceCodeInfo_Location{
+				// Spans spanning several lines have four elements
+				{Path: []int32{4, 0}, Span: []int32{4, 0, 7, 1}},
+				// Spans on a single line have three elements
+				{Path: []int32{4, 0, 2, 0}, Span: []int32{5, 2, 20}},
+			},
+		},
+	})
+
+	msg := d.Messages()[0]
+	fields := msg.Fields()
+	for _, test := range []struct {
+		sbj      string
+		expected Location
+		actual   Location
+		str      string
+	}{
+		{"message", Location{File: "loc.proto", StartLine: 5, StartColumn: 1, EndLine: 8, EndColumn: 2}, msg.Location, "loc.proto:5"},
+		{"field", Location{File: "loc.proto", StartLine: 6, StartColumn: 3, EndLine: 6, EndColumn: 21}, fields[0].Location, "loc.proto:6"},
+		{"field without location", Location{File: "loc.proto"}, fields[1].Location, "loc.proto"},
+	} {
+		testDiff(t, test.sbj, test.expected, test.actual)
+		if s := test.actual.String(); s != test.str {
+			t.Errorf("%s: expected %q, got %q", test.sbj, test.str, s)
+		}
+	}
+
+	// Without source info, entities only know which file they're in
+	d = testFileData(t, &descriptor.FileDescriptorProto{
+		Name:        proto.String("bare.proto"),
+		Package:     proto.String("bare"),
+		MessageType: []*descriptor.DescriptorProto{{Name: proto.String("Msg")}},
+	})
+	testDiff(t, "message without source info", Location{File: "bare.proto"}, d.Messages()[0].Location)
+}
@@ -0,0 +1,130 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Message returns the message with the given name, or nil if it isn't
+// defined.  Names starting with a `.` are fully-qualified, others are resolved
+// from the optional scope using C++-like scoping rules: first the types nested
+// in the scope are searched, then those in its parent, on up to the root
+// namespace.
+//
+// Example:
+//
+//	{{ with .Data.Message "google.protobuf.Timestamp" }}{{ .Name }}{{ end }}
+//	{{ with $.Data.Message "Item" .FullName }}{{ .FullName }}{{ end }}
+func (d *Data) Message(name string, scope ...string) *Message {
+	id, found := d.resolveName(name, lookupScope(scope), d.isMessage)
+	if !found {
+		return nil
+	}
+	return d.messages[messageID(id)]
+}
+
+// Enum returns the enum with the given name, or nil if it isn't defined.
+// Names are resolved like Message's.
+func (d *Data) Enum(name string, scope ...string) *Enum {
+	id, found := d.resolveName(name, lookupScope(scope), d.isEnum)
+	if !found {
+		return nil
+	}
+	return d.enums[enumID(id)]
+}
+
+// Service returns the service with the given name, or nil if it isn't
+// defined.  Names are resolved like Message's.
+func (d *Data) Service(name string, scope ...string) *Service {
+	id, found := d.resolveName(name, lookupScope(scope), d.isService)
+	if !found {
+		return nil
+	}
+	return d.services[serviceID(id)]
+}
+
+// Method returns the method with the given name, ie `pkg.Service.Method`, or
+// nil if it isn't defined.  The service's name is resolved like Message's.
+func (d *Data) Method(name string, scope ...string) *Method {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return nil
+	}
+	service := d.Service(name[:i], scope...)
+	if service == nil {
+		return nil
+	}
+	return d.methods[methodID(fmt.Sprintf("%s:%s", service.id, name[i+1:]))]
+}
+
+// File returns the file with the given name, ie `google/protobuf/timestamp.proto`,
+// or nil if it isn't defined
+func (d *Data) File(name string) *File {
+	for _, f := range d.files {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// lookupScope returns the fully-qualified scope given to a lookup function
+func lookupScope(scope []string) string {
+	if len(scope) == 0 {
+		return ""
+	}
+	return packageScope(strings.Trim(scope[0], "."))
+}
+
+// packageScope returns the fully-qualified scope of a package
+func packageScope(pkg string) string {
+	if pkg == "" {
+		return ""
+	}
+	return "." + pkg
+}
+
+// resolveName returns the fully-qualified name referred to by name from the
+// fully-qualified scope, using exists to check the kind of the resolved name.
+// As in protoc, the innermost scope defining the name's first component is
+// used, even if the rest of the name isn't defined there.
+func (d *Data) resolveName(name, scope string, exists func(string) bool) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+	if strings.HasPrefix(name, ".") {
+		return name, exists(name)
+	}
+
+	first := strings.SplitN(name, ".", 2)[0]
+	for {
+		if d.isSymbol(scope + "." + first) {
+			return scope + "." + name, exists(scope + "." + name)
+		}
+		if scope == "" {
+			return "", false
+		}
+		scope = scope[:strings.LastIndex(scope, ".")]
+	}
+}
+
+func (d *Data) isMessage(name string) bool {
+	_, found := d.messages[messageID(name)]
+	return found
+}
+
+func (d *Data) isEnum(name string) bool {
+	_, found := d.enums[enumID(name)]
+	return found
+}
+
+func (d *Data) isService(name string) bool {
+	_, found := d.services[serviceID(name)]
+	return found
+}
+
+// isSymbol returns true if the fully-qualified name is a package or defines a
+// scope
+func (d *Data) isSymbol(name string) bool {
+	return d.packages[name] || d.isMessage(name) || d.isEnum(name) || d.isService(name)
+}
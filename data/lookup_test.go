@@ -0,0 +1,94 @@
+package data
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestResolveName(t *testing.T) {
+	d := testFileData(t,
+		&descriptor.FileDescriptorProto{
+			Name:    proto.String("foo/bar.proto"),
+			Package: proto.String("foo.bar"),
+			MessageType: []*descriptor.DescriptorProto{
+				{
+					Name:       proto.String("Outer"),
+					NestedType: []*descriptor.DescriptorProto{{Name: proto.String("Inner")}},
+					EnumType:   []*descriptor.EnumDescriptorProto{{Name: proto.String("Kind")}},
+				},
+				{Name: proto.String("Inner")},
+			},
+		},
+		&descriptor.FileDescriptorProto{
+			Name:        proto.String("foo/baz.proto"),
+			Package:     proto.String("foo"),
+			MessageType: []*descriptor.DescriptorProto{{Name: proto.String("Baz")}},
+		},
+	)
+
+	for _, test := range []struct {
+		name, scope string
+		exists      func(string) bool
+		resolved    string
+		found       bool
+	}{
+		{"Inner", ".foo.bar.Outer", d.isMessage, ".foo.bar.Outer.Inner", true},
+		{"Inner", ".foo.bar", d.isMessage, ".foo.bar.Inner", true},
+		{"Baz", ".foo.bar.Outer", d.isMessage, ".foo.Baz", true},
+		{"bar.Inner", ".foo.bar.Outer", d.isMessage, ".foo.bar.Inner", true},
+		{"foo.Baz", "", d.isMessage, ".foo.Baz", true},
+		{".foo.Baz", ".foo.bar", d.isMessage, ".foo.Baz", true},
+		{".foo.Missing", "", d.isMessage, ".foo.Missing", false},
+		// The innermost scope defining `Outer` is used, even though it doesn't
+		// define `Baz`
+		{"Outer.Baz", ".foo.bar.Outer", d.isMessage, ".foo.bar.Outer.Baz", false},
+		{"Kind", ".foo.bar.Outer", d.isMessage, ".foo.bar.Outer.Kind", false},
+		{"Kind", ".foo.bar.Outer", d.isEnum, ".foo.bar.Outer.Kind", true},
+		{"Missing", ".foo.bar", d.isMessage, "", false},
+		{"", ".foo.bar", d.isMessage, "", false},
+	} {
+		resolved, found := d.resolveName(test.name, test.scope, test.exists)
+		if resolved != test.resolved || found != test.found {
+			t.Errorf("%q from %q: expected %q %t, got %q %t", test.name, test.scope, test.resolved, test.found, resolved, found)
+		}
+	}
+}
+
+func TestRelativeTypeNames(t *testing.T) {
+	optional := descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+	d := testFileData(t, &descriptor.FileDescriptorProto{
+		Name:    proto.String("foo/bar.proto"),
+		Package: proto.String("foo.bar"),
+		MessageType: []*descriptor.DescriptorProto{{
+			Name: proto.String("Outer"),
+			Field: []*descriptor.FieldDescriptorProto{
+				testField("inner", 1, optional, descriptor.FieldDescriptorProto_TYPE_MESSAGE, "Inner"),
+				testField("kind", 2, optional, descriptor.FieldDescriptorProto_TYPE_ENUM, "Outer.Kind"),
+			},
+			NestedType: []*descriptor.DescriptorProto{{Name: proto.String("Inner")}},
+			EnumType:   []*descriptor.EnumDescriptorProto{{Name: proto.String("Kind")}},
+		}},
+		Service: []*descriptor.ServiceDescriptorProto{{
+			Name: proto.String("Svc"),
+			Method: []*descriptor.MethodDescriptorProto{{
+				Name:       proto.String("Call"),
+				InputType:  proto.String("Outer"),
+				OutputType: proto.String("bar.Outer.Inner"),
+			}},
+		}},
+	})
+
+	fields := d.Message("foo.bar.Outer").Fields()
+	if name := fields[0].TypeMessage().FullName(); name != "foo.bar.Outer.Inner" {
+		t.Errorf("expected field type foo.bar.Outer.Inner, got %s", name)
+	}
+	if name := fields[1].TypeEnum().FullName(); name != "foo.bar.Outer.Kind" {
+		t.Errorf("expected field type foo.bar.Outer.Kind, got %s", name)
+	}
+	method := d.Method("foo.bar.Svc.Call")
+	if input, output := method.InputType().FullName(), method.OutputType().FullName(); input != "foo.bar.Outer" || output != "foo.bar.Outer.Inner" {
+		t.Errorf("expected method types foo.bar.Outer and foo.bar.Outer.Inner, got %s and %s", input, output)
+	}
+}
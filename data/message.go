@@ -2,6 +2,7 @@ package data
 
 import (
 	"sort"
+	"strings"
 
 	"github.com/kerinin/protoc-gen-template/meta"
 	"google.golang.org/protobuf/proto"
@@ -59,28 +60,37 @@ func (s MessageSlice) NotNested() MessageSlice {
 
 // Message describes a protobuf message definition
 type Message struct {
-	idx      int
-	id       messageID
-	data     *Data
-	file     fileID
-	parent   messageID // Non-empty for embedded messages
-	fields   []fieldID
-	messages []messageID
-	enums    []enumID
-	oneofs   []oneofID
+	idx        int
+	id         messageID
+	data       *Data
+	file       fileID
+	parent     messageID // Non-empty for embedded messages
+	fields     []fieldID
+	messages   []messageID
+	enums      []enumID
+	oneofs     []oneofID
+	extensions []extensionID
 
 	Name          string
 	Meta          meta.MessageMetadata      // Custom metadata extensions defined for protoc-gen-template
 	Options       descriptor.MessageOptions // Globally-defined message metadata
 	Comments      Comments
+	Location      Location // Where the message is defined
 	ReservedTags  []descriptor.DescriptorProto_ReservedRange
 	ReservedNames []string // Reserved field names, which may not be used by fields in the same message.
+
+	ExtensionRanges []ExtensionRange // Field numbers available to extensions of the message
 }
 
 func (m Message) String() string {
 	return string(m.id)
 }
 
+// FullName returns the message's fully-qualified name, ie "pkg.Msg.Nested"
+func (m Message) FullName() string {
+	return strings.TrimPrefix(string(m.id), ".")
+}
+
 // IsVisible returns true if the message's file and any enclosing message are
 // visible, and its visibility metadata is `PUBLIC`
 func (m Message) IsVisible() bool {
@@ -105,6 +115,11 @@ func (m Message) IsDeprecated() bool {
 	return m.Options.Deprecated != nil && *m.Options.Deprecated == true
 }
 
+// Data returns the Data describing the whole code generator request
+func (m Message) Data() *Data {
+	return m.data
+}
+
 // File returns the containing file
 func (m Message) File() File {
 	return *m.data.files[m.file]
@@ -120,6 +135,22 @@ func (m Message) IsNested() bool {
 	return m.parent != messageID("")
 }
 
+// IsMapEntry returns true if the message is the synthetic entry type
+// generated for a map field.  Map entries are omitted from message slices, but
+// can be reached through the map field's TypeMessage.
+func (m Message) IsMapEntry() bool {
+	return m.Options.GetMapEntry()
+}
+
+// Features returns the message's resolved features, inherited from its file
+// and any enclosing messages
+func (m Message) Features() Features {
+	if t := m.Parent(); t != nil {
+		return t.Features().merge(m.Options.GetFeatures())
+	}
+	return m.File().Features().merge(m.Options.GetFeatures())
+}
+
 // Root returns the outermost ancestor of the message
 func (m Message) Root() Message {
 	if m.parent == messageID("") {
@@ -175,10 +206,13 @@ func (m Message) scalars(ancestors []messageID) ScalarPathSlice {
 	return vs
 }
 
-// Messages returns a slice of nested messages
+// Messages returns a slice of nested messages, excluding map entries
 func (m Message) Messages() MessageSlice {
 	vs := make([]Message, 0, len(m.messages))
 	for _, v := range m.messages {
+		if m.data.messages[v].IsMapEntry() {
+			continue
+		}
 		vs = append(vs, *m.data.messages[v])
 	}
 	sort.Sort(sortedMessagesByIndex(vs))
@@ -191,15 +225,56 @@ func (m Message) Enums() EnumSlice {
 	for _, v := range m.enums {
 		vs = append(vs, *m.data.enums[v])
 	}
+	sort.Sort(sortedEnumsByIndex(vs))
 	return vs
 }
 
-// Oneofs returns a slice of the message's oneofs
+// Oneofs returns a slice of the message's oneofs, excluding synthetic oneofs
 func (m Message) Oneofs() OneofSlice {
 	vs := make([]Oneof, 0, len(m.oneofs))
 	for _, v := range m.oneofs {
+		if m.data.oneofs[v].synthetic {
+			continue
+		}
 		vs = append(vs, *m.data.oneofs[v])
 	}
+	sort.Sort(sortedOneofsByIndex(vs))
+	return vs
+}
+
+// SyntheticOneofs returns a slice of the message's synthetic oneofs, each
+// containing a proto3 optional field
+func (m Message) SyntheticOneofs() OneofSlice {
+	vs := make([]Oneof, 0)
+	for _, v := range m.oneofs {
+		if m.data.oneofs[v].synthetic {
+			vs = append(vs, *m.data.oneofs[v])
+		}
+	}
+	sort.Sort(sortedOneofsByIndex(vs))
+	return vs
+}
+
+// Extensions returns a slice of the extensions declared in the message, which
+// may extend any message
+func (m Message) Extensions() ExtensionSlice {
+	vs := make([]Extension, 0, len(m.extensions))
+	for _, v := range m.extensions {
+		vs = append(vs, *m.data.extensions[v])
+	}
+	sort.Sort(sortedExtensionsByIndex(vs))
+	return vs
+}
+
+// ExtendedBy returns a slice of the extensions extending the message
+func (m Message) ExtendedBy() ExtensionSlice {
+	vs := make([]Extension, 0)
+	for _, v := range m.data.extensions {
+		if v.extendee == m.id {
+			vs = append(vs, *v)
+		}
+	}
+	sort.Sort(sortedExtensionsByIndex(vs))
 	return vs
 }
 
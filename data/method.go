@@ -35,6 +35,7 @@ func (s MethodSlice) NotDeprecated() MethodSlice {
 
 // Method describes a protobuf service method
 type Method struct {
+	idx        int
 	id         methodID
 	data       *Data
 	parent     serviceID
@@ -45,6 +46,7 @@ type Method struct {
 	Meta            meta.MethodMetadata      // Custom metadata extensions defined for protoc-gen-template
 	Options         descriptor.MethodOptions // Globally-defined service metadata
 	Comments        Comments
+	Location        Location // Where the method is defined
 	ClientStreaming bool
 	ServerStreaming bool
 }
@@ -83,6 +85,11 @@ func (m Method) IsDeprecated() bool {
 	return m.Options.Deprecated != nil && *m.Options.Deprecated == true
 }
 
+// FullName returns the method's fully-qualified name, ie "pkg.Service.Method"
+func (m Method) FullName() string {
+	return m.Parent().FullName() + "." + m.Name
+}
+
 // Parent returns the method's parent service
 func (m Method) Parent() Service {
 	return *m.data.services[m.parent]
@@ -117,3 +124,9 @@ func derefMethodOptions(o *descriptor.MethodOptions) (_ descriptor.MethodOptions
 	}
 	return *o
 }
+
+type sortedMethodsByIndex []Method
+
+func (s sortedMethodsByIndex) Len() int           { return len(s) }
+func (s sortedMethodsByIndex) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortedMethodsByIndex) Less(i, j int) bool { return s[i].idx < s[j].idx }
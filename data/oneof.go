@@ -35,14 +35,18 @@ func (s OneofSlice) NotDeprecated() OneofSlice {
 
 // Oneof describes a protobuf message definition
 type Oneof struct {
+	idx    int
 	id     oneofID
 	data   *Data
 	parent messageID
 	fields []fieldID
 
+	synthetic bool // True for the oneof containing a proto3 optional field
+
 	Name     string
 	Options  descriptor.OneofOptions // Globally-defined message metadata
 	Comments Comments
+	Location Location // Where the oneof is defined
 }
 
 func (o Oneof) String() string {
@@ -66,6 +70,17 @@ func (o Oneof) IsDeprecated() bool {
 	return false
 }
 
+// FullName returns the oneof's fully-qualified name, ie "pkg.Msg.oneof"
+func (o Oneof) FullName() string {
+	return o.Parent().FullName() + "." + o.Name
+}
+
+// IsSynthetic returns true if the oneof was generated by protoc to contain a
+// proto3 optional field, rather than declared in the source
+func (o Oneof) IsSynthetic() bool {
+	return o.synthetic
+}
+
 // Parent returns the method's parent service
 func (o Oneof) Parent() Message {
 	return *o.data.messages[o.parent]
@@ -90,3 +105,9 @@ func derefOneofOptions(o *descriptor.OneofOptions) (_ descriptor.OneofOptions) {
 	}
 	return *o
 }
+
+type sortedOneofsByIndex []Oneof
+
+func (s sortedOneofsByIndex) Len() int           { return len(s) }
+func (s sortedOneofsByIndex) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortedOneofsByIndex) Less(i, j int) bool { return s[i].idx < s[j].idx }
@@ -0,0 +1,156 @@
+package data
+
+import (
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Option returns the value of a custom option set on a File, Message, Field,
+// Extension, Oneof, Enum, EnumValue, Service or Method, or nil if the option
+// isn't set.  Options are named by the full name of their extension, and are
+// decoded using the extensions defined in the request's files.
+//
+// Scalar values are returned as Go values, enum values as their name, and
+// messages as a map from field names to values.  Repeated values are returned
+// as slices.
+func (d *Data) Option(v interface{}, name string) (interface{}, error) {
+	opts, err := entityOptions(v)
+	if err != nil {
+		return nil, err
+	}
+
+	types, err := d.optionTypes()
+	if err != nil {
+		return nil, err
+	}
+	xt, err := types.FindExtensionByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, errors.Errorf("unknown option %s", name)
+	}
+	xd := xt.TypeDescriptor()
+	if extendee := opts.ProtoReflect().Descriptor().FullName(); xd.ContainingMessage().FullName() != extendee {
+		return nil, errors.Errorf("option %s extends %s, not %s", name, xd.ContainingMessage().FullName(), extendee)
+	}
+
+	// NOTE: Options are re-parsed with the request's extensions, as extensions
+	// unknown to this binary are stored as unknown fields.
+	b, err := proto.Marshal(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshaling options")
+	}
+	m := dynamicpb.NewMessage(xd.ContainingMessage())
+	if err := (proto.UnmarshalOptions{Resolver: types}).Unmarshal(b, m); err != nil {
+		return nil, errors.Wrap(err, "unmarshaling options")
+	}
+
+	if !m.Has(xd) {
+		return nil, nil
+	}
+	return optionValue(xd, m.Get(xd)), nil
+}
+
+// optionTypes returns the types defined in the request's files, building them
+// on first use
+func (d *Data) optionTypes() (*dynamicpb.Types, error) {
+	if d.types == nil {
+		files, err := protodesc.FileOptions{AllowUnresolvable: true}.NewFiles(&descriptor.FileDescriptorSet{File: d.protoFiles})
+		if err != nil {
+			return nil, errors.Wrap(err, "building file descriptors")
+		}
+		d.types = dynamicpb.NewTypes(files)
+	}
+	return d.types, nil
+}
+
+// entityOptions returns the options message of an entity
+func entityOptions(v interface{}) (proto.Message, error) {
+	switch v := v.(type) {
+	case File:
+		return &v.Options, nil
+	case *File:
+		return &v.Options, nil
+	case Message:
+		return &v.Options, nil
+	case *Message:
+		return &v.Options, nil
+	case Field:
+		return &v.Options, nil
+	case *Field:
+		return &v.Options, nil
+	case Extension:
+		return &v.Options, nil
+	case *Extension:
+		return &v.Options, nil
+	case Oneof:
+		return &v.Options, nil
+	case *Oneof:
+		return &v.Options, nil
+	case Enum:
+		return &v.Options, nil
+	case *Enum:
+		return &v.Options, nil
+	case EnumValue:
+		return &v.Options, nil
+	case *EnumValue:
+		return &v.Options, nil
+	case Service:
+		return &v.Options, nil
+	case *Service:
+		return &v.Options, nil
+	case Method:
+		return &v.Options, nil
+	case *Method:
+		return &v.Options, nil
+	default:
+		return nil, errors.Errorf("%T doesn't have options", v)
+	}
+}
+
+// optionValue converts a field's value to a value templates can use
+func optionValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		vs := make([]interface{}, 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			vs = append(vs, singularOptionValue(fd, list.Get(i)))
+		}
+		return vs
+
+	case fd.IsMap():
+		vs := map[string]interface{}{}
+		v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			vs[k.String()] = singularOptionValue(fd.MapValue(), v)
+			return true
+		})
+		return vs
+
+	default:
+		return singularOptionValue(fd, v)
+	}
+}
+
+func singularOptionValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return int32(v.Enum())
+
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		vs := map[string]interface{}{}
+		v.Message().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+			vs[string(fd.Name())] = optionValue(fd, v)
+			return true
+		})
+		return vs
+
+	default:
+		return v.Interface()
+	}
+}
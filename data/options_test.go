@@ -0,0 +1,120 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestOption(t *testing.T) {
+	var (
+		optional = descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+		repeated = descriptor.FieldDescriptorProto_LABEL_REPEATED
+		str      = descriptor.FieldDescriptorProto_TYPE_STRING
+		extend   = func(f *descriptor.FieldDescriptorProto, extendee string) *descriptor.FieldDescriptorProto {
+			f.Extendee = proto.String(extendee)
+			return f
+		}
+	)
+
+	// The options are unknown to this binary, so they're stored as unknown
+	// fields, as they are when protoc sends them
+	var owner []byte
+	owner = protowire.AppendTag(owner, 1, protowire.BytesType)
+	owner = protowire.AppendString(owner, "me")
+	for _, tag := range []string{"a", "b"} {
+		owner = protowire.AppendTag(owner, 2, protowire.BytesType)
+		owner = protowire.AppendString(owner, tag)
+	}
+	var fieldOptions, messageOptions []byte
+	fieldOptions = protowire.AppendTag(fieldOptions, 50200, protowire.VarintType)
+	fieldOptions = protowire.AppendVarint(fieldOptions, 1)
+	fieldOptions = protowire.AppendTag(fieldOptions, 50201, protowire.VarintType)
+	fieldOptions = protowire.AppendVarint(fieldOptions, 2)
+	messageOptions = protowire.AppendTag(messageOptions, 50202, protowire.BytesType)
+	messageOptions = protowire.AppendBytes(messageOptions, owner)
+
+	password := testField("password", 1, optional, str, "")
+	password.Options = &descriptor.FieldOptions{}
+	password.Options.ProtoReflect().SetUnknown(fieldOptions)
+	user := &descriptor.DescriptorProto{
+		Name:    proto.String("User"),
+		Options: &descriptor.MessageOptions{},
+		Field:   []*descriptor.FieldDescriptorProto{password, testField("name", 2, optional, str, "")},
+	}
+	user.Options.ProtoReflect().SetUnknown(messageOptions)
+
+	d := testFileData(t,
+		protodesc.ToFileDescriptorProto(descriptor.File_google_protobuf_descriptor_proto),
+		&descriptor.FileDescriptorProto{
+			Name:       proto.String("acme/options.proto"),
+			Package:    proto.String("acme"),
+			Syntax:     proto.String("proto3"),
+			Dependency: []string{"google/protobuf/descriptor.proto"},
+			EnumType: []*descriptor.EnumDescriptorProto{{
+				Name: proto.String("Level"),
+				Value: []*descriptor.EnumValueDescriptorProto{
+					{Name: proto.String("LEVEL_UNSPECIFIED"), Number: proto.Int32(0)},
+					{Name: proto.String("LEVEL_LOW"), Number: proto.Int32(1)},
+					{Name: proto.String("LEVEL_HIGH"), Number: proto.Int32(2)},
+				},
+			}},
+			Extension: []*descriptor.FieldDescriptorProto{
+				extend(testField("sensitive", 50200, optional, descriptor.FieldDescriptorProto_TYPE_BOOL, ""), ".google.protobuf.FieldOptions"),
+				extend(testField("level", 50201, optional, descriptor.FieldDescriptorProto_TYPE_ENUM, ".acme.Level"), ".google.protobuf.FieldOptions"),
+				extend(testField("owner", 50202, optional, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".acme.Owner"), ".google.protobuf.MessageOptions"),
+			},
+			MessageType: []*descriptor.DescriptorProto{
+				{
+					Name: proto.String("Owner"),
+					Field: []*descriptor.FieldDescriptorProto{
+						testField("name", 1, optional, str, ""),
+						testField("tags", 2, repeated, str, ""),
+					},
+				},
+				user,
+			},
+		},
+	)
+
+	var (
+		message = d.Message("acme.User")
+		fields  = message.Fields()
+	)
+	for _, test := range []struct {
+		entity   interface{}
+		name     string
+		expected interface{}
+	}{
+		{fields[0], "acme.sensitive", true},
+		{&fields[0], "acme.level", "LEVEL_HIGH"},
+		{fields[1], "acme.sensitive", nil},
+		{message, "acme.owner", map[string]interface{}{"name": "me", "tags": []interface{}{"a", "b"}}},
+	} {
+		actual, err := d.Option(test.entity, test.name)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: expected %#v, got %#v", test.name, test.expected, actual)
+		}
+	}
+
+	for _, test := range []struct {
+		entity interface{}
+		name   string
+	}{
+		{fields[0], "acme.missing"},
+		{message, "acme.sensitive"},
+		{d, "acme.sensitive"},
+	} {
+		if _, err := d.Option(test.entity, test.name); err == nil {
+			t.Errorf("%T %s: expected error", test.entity, test.name)
+		}
+	}
+}
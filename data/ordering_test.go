@@ -0,0 +1,127 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// testOrderingFiles returns files declaring everything in reverse alphabetical
+// order, so that sorting by name can't pass for declaration order
+func testOrderingFiles() []*descriptor.FileDescriptorProto {
+	var (
+		optional = descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+		str      = descriptor.FieldDescriptorProto_TYPE_STRING
+		zulu     = testField("zulu", 1, optional, str, "")
+		alpha    = testField("alpha", 2, optional, str, "")
+		ext      = testField("ext", 100, optional, str, "")
+	)
+	zulu.OneofIndex = proto.Int32(0)
+	alpha.OneofIndex = proto.Int32(1)
+	ext.Extendee = proto.String(".z.Zebra")
+
+	enum := func(name string, values ...string) *descriptor.EnumDescriptorProto {
+		e := &descriptor.EnumDescriptorProto{Name: proto.String(name)}
+		for i, v := range values {
+			e.Value = append(e.Value, &descriptor.EnumValueDescriptorProto{Name: proto.String(v), Number: proto.Int32(int32(i))})
+		}
+		return e
+	}
+	method := func(name string) *descriptor.MethodDescriptorProto {
+		return &descriptor.MethodDescriptorProto{Name: proto.String(name), InputType: proto.String(".z.Ant"), OutputType: proto.String(".z.Ant")}
+	}
+
+	return []*descriptor.FileDescriptorProto{
+		{
+			Name:    proto.String("z.proto"),
+			Package: proto.String("z"),
+			MessageType: []*descriptor.DescriptorProto{
+				{
+					Name:           proto.String("Zebra"),
+					Field:          []*descriptor.FieldDescriptorProto{zulu, alpha},
+					OneofDecl:      []*descriptor.OneofDescriptorProto{{Name: proto.String("zz")}, {Name: proto.String("aa")}},
+					NestedType:     []*descriptor.DescriptorProto{{Name: proto.String("Yak")}},
+					EnumType:       []*descriptor.EnumDescriptorProto{enum("Walrus", "WALRUS_Z", "WALRUS_A")},
+					ExtensionRange: []*descriptor.DescriptorProto_ExtensionRange{{Start: proto.Int32(100), End: proto.Int32(200)}},
+				},
+				{Name: proto.String("Ant")},
+			},
+			EnumType: []*descriptor.EnumDescriptorProto{enum("Zed", "ZED_Z", "ZED_A"), enum("Abc", "ABC_Z")},
+			Service: []*descriptor.ServiceDescriptorProto{
+				{Name: proto.String("Zoo"), Method: []*descriptor.MethodDescriptorProto{method("Zap"), method("Add")}},
+				{Name: proto.String("Ark"), Method: []*descriptor.MethodDescriptorProto{method("Yell")}},
+			},
+			Extension: []*descriptor.FieldDescriptorProto{ext},
+		},
+		{
+			Name:        proto.String("a.proto"),
+			Package:     proto.String("a"),
+			MessageType: []*descriptor.DescriptorProto{{Name: proto.String("Bee")}},
+		},
+	}
+}
+
+// orderedNames returns the full names of each of d's collections, in the order
+// d lists them
+func orderedNames(d *Data) map[string]string {
+	names := map[string][]string{}
+	for _, v := range d.Files() {
+		names["files"] = append(names["files"], v.Name)
+	}
+	for _, v := range d.Messages() {
+		names["messages"] = append(names["messages"], v.FullName())
+	}
+	for _, v := range d.Fields() {
+		names["fields"] = append(names["fields"], v.FullName())
+	}
+	for _, v := range d.Oneofs() {
+		names["oneofs"] = append(names["oneofs"], v.FullName())
+	}
+	for _, v := range d.Enums() {
+		names["enums"] = append(names["enums"], v.FullName())
+	}
+	for _, v := range d.EnumValues() {
+		names["enum values"] = append(names["enum values"], v.FullName())
+	}
+	for _, v := range d.Services() {
+		names["services"] = append(names["services"], v.FullName())
+	}
+	for _, v := range d.Methods() {
+		names["methods"] = append(names["methods"], v.FullName())
+	}
+	for _, v := range d.Extensions() {
+		names["extensions"] = append(names["extensions"], v.FullName())
+	}
+
+	joined := make(map[string]string, len(names))
+	for k, v := range names {
+		joined[k] = strings.Join(v, " ")
+	}
+	return joined
+}
+
+func TestOrdering(t *testing.T) {
+	expected := map[string]string{
+		"files":       "z.proto a.proto",
+		"messages":    "z.Zebra z.Zebra.Yak z.Ant a.Bee",
+		"fields":      "z.Zebra.zulu z.Zebra.alpha",
+		"oneofs":      "z.Zebra.zz z.Zebra.aa",
+		"enums":       "z.Zebra.Walrus z.Zed z.Abc",
+		"enum values": "z.Zebra.WALRUS_Z z.Zebra.WALRUS_A z.ZED_Z z.ZED_A z.ABC_Z",
+		"services":    "z.Zoo z.Ark",
+		"methods":     "z.Zoo.Zap z.Zoo.Add z.Ark.Yell",
+		"extensions":  "z.ext",
+	}
+
+	// Map iteration order varies between runs, so read the request repeatedly
+	for i := 0; i < 10; i++ {
+		actual := orderedNames(testFileData(t, testOrderingFiles()...))
+		for k, v := range expected {
+			if actual[k] != v {
+				t.Fatalf("expected %s %q, got %q", k, v, actual[k])
+			}
+		}
+	}
+}
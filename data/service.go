@@ -1,6 +1,9 @@
 package data
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/kerinin/protoc-gen-template/meta"
 	"google.golang.org/protobuf/proto"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
@@ -46,6 +49,7 @@ func (s ServiceSlice) ToGenerate() ServiceSlice {
 
 // Service describes a protobuf service
 type Service struct {
+	idx     int
 	id      serviceID
 	data    *Data
 	file    fileID
@@ -55,6 +59,7 @@ type Service struct {
 	Meta     meta.ServiceMetadata      // Custom metadata extensions defined for protoc-gen-template
 	Options  descriptor.ServiceOptions // Globally-defined service metadata
 	Comments Comments
+	Location Location // Where the service is defined
 }
 
 func (s Service) String() string {
@@ -79,6 +84,16 @@ func (s Service) IsDeprecated() bool {
 	return s.Options.Deprecated != nil && *s.Options.Deprecated == true
 }
 
+// FullName returns the service's fully-qualified name, ie "pkg.Service"
+func (s Service) FullName() string {
+	return strings.TrimPrefix(string(s.id), ".")
+}
+
+// Data returns the Data describing the whole code generator request
+func (s Service) Data() *Data {
+	return s.data
+}
+
 // File returns the containing file
 func (s Service) File() File {
 	return *s.data.files[s.file]
@@ -90,6 +105,7 @@ func (s Service) Methods() MethodSlice {
 	for _, v := range s.methods {
 		vs = append(vs, *s.data.methods[v])
 	}
+	sort.Sort(sortedMethodsByIndex(vs))
 	return vs
 }
 
@@ -112,3 +128,9 @@ func derefServiceOptions(o *descriptor.ServiceOptions) (_ descriptor.ServiceOpti
 	}
 	return *o
 }
+
+type sortedServicesByIndex []Service
+
+func (s sortedServicesByIndex) Len() int           { return len(s) }
+func (s sortedServicesByIndex) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortedServicesByIndex) Less(i, j int) bool { return s[i].idx < s[j].idx }
@@ -0,0 +1,103 @@
+package data
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	plugin "google.golang.org/protobuf/types/pluginpb"
+)
+
+// testUnresolvedFile returns a file whose message, enum, service and
+// extension types all resolve
+func testUnresolvedFile() *descriptor.FileDescriptorProto {
+	optional := descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+	ext := testField("note", 100, optional, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".pkg.Msg")
+	ext.Extendee = proto.String(".pkg.Msg")
+	return &descriptor.FileDescriptorProto{
+		Name:    proto.String("pkg/pkg.proto"),
+		Package: proto.String("pkg"),
+		MessageType: []*descriptor.DescriptorProto{{
+			Name: proto.String("Msg"),
+			Field: []*descriptor.FieldDescriptorProto{
+				testField("child", 1, optional, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".pkg.Msg"),
+				testField("kind", 2, optional, descriptor.FieldDescriptorProto_TYPE_ENUM, ".pkg.Kind"),
+			},
+			ExtensionRange: []*descriptor.DescriptorProto_ExtensionRange{{Start: proto.Int32(100), End: proto.Int32(200)}},
+		}},
+		EnumType: []*descriptor.EnumDescriptorProto{{
+			Name:  proto.String("Kind"),
+			Value: []*descriptor.EnumValueDescriptorProto{{Name: proto.String("KIND_UNKNOWN"), Number: proto.Int32(0)}},
+		}},
+		Service: []*descriptor.ServiceDescriptorProto{{
+			Name: proto.String("Svc"),
+			Method: []*descriptor.MethodDescriptorProto{{
+				Name:       proto.String("Call"),
+				InputType:  proto.String(".pkg.Msg"),
+				OutputType: proto.String(".pkg.Msg"),
+			}},
+		}},
+		Extension: []*descriptor.FieldDescriptorProto{ext},
+	}
+}
+
+func TestUnresolved(t *testing.T) {
+	testFileData(t, testUnresolvedFile())
+
+	for _, test := range []struct {
+		name, message string
+		edit          func(f *descriptor.FileDescriptorProto)
+	}{
+		{
+			name:    ".pkg.Missing",
+			message: "pkg/pkg.proto: field pkg.Msg.child refers to unresolved type .pkg.Missing",
+			edit: func(f *descriptor.FileDescriptorProto) {
+				f.MessageType[0].Field[0].TypeName = proto.String(".pkg.Missing")
+			},
+		},
+		{
+			name:    ".pkg.Missing",
+			message: "pkg/pkg.proto: field pkg.Msg.kind refers to unresolved type .pkg.Missing",
+			edit: func(f *descriptor.FileDescriptorProto) {
+				f.MessageType[0].Field[1].TypeName = proto.String(".pkg.Missing")
+			},
+		},
+		{
+			name:    "Missing",
+			message: "pkg/pkg.proto: input of method pkg.Svc.Call refers to unresolved type Missing",
+			edit:    func(f *descriptor.FileDescriptorProto) { f.Service[0].Method[0].InputType = proto.String("Missing") },
+		},
+		{
+			name:    ".other.Msg",
+			message: "pkg/pkg.proto: output of method pkg.Svc.Call refers to unresolved type .other.Msg",
+			edit: func(f *descriptor.FileDescriptorProto) {
+				f.Service[0].Method[0].OutputType = proto.String(".other.Msg")
+			},
+		},
+		{
+			name:    ".pkg.Missing",
+			message: "pkg/pkg.proto: extendee of extension pkg.note refers to unresolved type .pkg.Missing",
+			edit:    func(f *descriptor.FileDescriptorProto) { f.Extension[0].Extendee = proto.String(".pkg.Missing") },
+		},
+		{
+			name:    ".pkg.Missing",
+			message: "pkg/pkg.proto: extension pkg.note refers to unresolved type .pkg.Missing",
+			edit:    func(f *descriptor.FileDescriptorProto) { f.Extension[0].TypeName = proto.String(".pkg.Missing") },
+		},
+	} {
+		f := testUnresolvedFile()
+		test.edit(f)
+		_, err := New(&plugin.CodeGeneratorRequest{ProtoFile: []*descriptor.FileDescriptorProto{f}})
+		unresolved, ok := err.(*UnresolvedError)
+		if !ok {
+			t.Errorf("%s: expected *UnresolvedError, got %#v", test.name, err)
+			continue
+		}
+		if unresolved.File != "pkg/pkg.proto" || unresolved.Name != test.name {
+			t.Errorf("expected pkg/pkg.proto and %s, got %s and %s", test.name, unresolved.File, unresolved.Name)
+		}
+		if err.Error() != test.message {
+			t.Errorf("expected %q, got %q", test.message, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// captureRequest writes the request to filename in binary form and alongside
+// it (with a `.json` extension) as JSON, in the same format as
+// `data/testdata/dump.pb` and `data/testdata/dump.json`.  The binary capture
+// can be rendered again with the `render` subcommand, so the `capture`
+// parameter is removed from the captured request.
+func captureRequest(req *plugin.CodeGeneratorRequest, filename string) error {
+	parameter := removeParameter(req.GetParameter(), captureParam)
+	req = proto.Clone(req).(*plugin.CodeGeneratorRequest)
+	req.Parameter = nil
+	if parameter != "" {
+		req.Parameter = proto.String(parameter)
+	}
+
+	b, err := proto.Marshal(req)
+	if err != nil {
+		return errors.Wrap(err, "marshaling request")
+	}
+
+	j, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(proto.MessageV2(req))
+	if err != nil {
+		return errors.Wrap(err, "marshaling request as JSON")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return errors.Wrap(err, "creating directory")
+	}
+	if err := ioutil.WriteFile(filename, b, 0644); err != nil {
+		return errors.Wrap(err, "writing request")
+	}
+
+	jsonFilename := strings.TrimSuffix(filename, filepath.Ext(filename)) + ".json"
+	if jsonFilename == filename {
+		jsonFilename += ".json"
+	}
+	if err := ioutil.WriteFile(jsonFilename, j, 0644); err != nil {
+		return errors.Wrap(err, "writing request as JSON")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/kerinin/protoc-gen-template/data"
+	"github.com/pkg/errors"
+)
+
+var (
+	// expansionTargets maps template name suffixes to the Data methods
+	// returning the values a template is expanded over
+	expansionTargets = map[string]string{
+		"files":    "Files",
+		"messages": "Messages",
+		"enums":    "Enums",
+		"services": "Services",
+	}
+
+	// expansionFilters maps template name suffixes to the slice methods used
+	// to filter expanded values.  Values are limited to those defined in files
+	// to generate unless "all" is given.
+	expansionFilters = map[string]string{
+		"all":           "",
+		"togenerate":    "ToGenerate",
+		"visible":       "Visible",
+		"notdeprecated": "NotDeprecated",
+		"notnested":     "NotNested",
+	}
+)
+
+// expansion describes the set of values a template is executed against, once
+// per value
+type expansion struct {
+	target  string   // Data method returning the values, ie "Messages"
+	filters []string // Slice methods applied to the values, ie "ToGenerate"
+}
+
+// parseExpansion extracts an expansion from the suffixes of a template path
+// (with the `.tmpl` suffix already removed), returning the path without them.
+//
+// Examples:
+//
+//	"{{.Name}}.md"                         -> files, ToGenerate
+//	"{{.Name}}.md.messages"                -> messages, ToGenerate
+//	"{{.Name}}.md.messages.visible"        -> messages, ToGenerate, Visible
+//	"{{.Name}}.md.enums.all"               -> enums
+//	"{{.Name}}.md.enums.all.notnested"     -> enums, NotNested
+//	"index.md"                             -> nil
+func parseExpansion(outPath string) (string, *expansion) {
+	var (
+		trimmed = outPath
+		filters = []string{}
+		all     = false
+	)
+	for {
+		ext := strings.TrimPrefix(pathExt(trimmed), ".")
+		method, found := expansionFilters[ext]
+		if !found {
+			break
+		}
+		if method == "" {
+			all = true
+		} else {
+			filters = append([]string{method}, filters...)
+		}
+		trimmed = strings.TrimSuffix(trimmed, "."+ext)
+	}
+
+	ext := strings.TrimPrefix(pathExt(trimmed), ".")
+	target, found := expansionTargets[ext]
+	if !found {
+		if strings.Contains(outPath, `{{`) {
+			return outPath, &expansion{target: "Files", filters: []string{"ToGenerate"}}
+		}
+		return outPath, nil
+	}
+
+	if !all {
+		filters = append([]string{"ToGenerate"}, filters...)
+	}
+	return strings.TrimSuffix(trimmed, "."+ext), &expansion{target: target, filters: filters}
+}
+
+// values returns the values described by the expansion.  Values are pointers
+// to avoid copying the descriptor options they contain.
+func (e *expansion) values(d *data.Data) ([]interface{}, error) {
+	method := reflect.ValueOf(d).MethodByName(e.target)
+	if !method.IsValid() {
+		return nil, errors.Errorf("unknown expansion target %s", e.target)
+	}
+	slice := method.Call(nil)[0]
+
+	for _, filter := range e.filters {
+		method := slice.MethodByName(filter)
+		if !method.IsValid() {
+			return nil, errors.Errorf("%s cannot be filtered by %s", e.target, filter)
+		}
+		slice = method.Call(nil)[0]
+	}
+
+	values := make([]interface{}, 0, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		values = append(values, slice.Index(i).Addr().Interface())
+	}
+	return values, nil
+}
+
+// pathExt returns the extension of the final path element, ignoring any
+// dots inside template actions
+func pathExt(s string) string {
+	if i := strings.LastIndex(s, `}}`); i >= 0 {
+		s = s[i+2:]
+	}
+	if i := strings.LastIndexAny(s, `./`); i >= 0 && s[i] == '.' {
+		return s[i:]
+	}
+	return ""
+}
@@ -0,0 +1,198 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/scanner"
+	"io"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/imports"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// autoFormat selects a formatter from the output path's extension
+const autoFormat = "auto"
+
+// formatter post-processes the output of a template
+type formatter func(filename, content string) (string, error)
+
+var (
+	// formatters maps format names to formatters
+	formatters = map[string]formatter{
+		"none":      formatNone,
+		"text":      formatText,
+		"gofmt":     formatGo,
+		"goimports": formatGoImports,
+		"json":      formatJSON,
+		"yaml":      formatYAML,
+	}
+
+	// formatsByExt maps output extensions to the format applied when the
+	// format is `auto`.  Other extensions are formatted as `text`.
+	formatsByExt = map[string]string{
+		".go":   "gofmt",
+		".json": "json",
+		".yaml": "yaml",
+		".yml":  "yaml",
+	}
+)
+
+// formatOutput applies the named format to content.  Insertions are fragments
+// of another file, so only `text` formatting is applied to them automatically.
+// Content containing only whitespace is returned unchanged, so conditionally
+// empty outputs can be dropped by skip_empty whatever their format.
+func formatOutput(format, filename, content string, insertion bool) (string, error) {
+	if format == "" || format == autoFormat {
+		format = "text"
+		if ext, found := formatsByExt[path.Ext(filename)]; found && !insertion {
+			format = ext
+		}
+	}
+
+	fn, found := formatters[format]
+	if !found {
+		return "", errors.Errorf("unknown format %q", format)
+	}
+	if strings.TrimSpace(content) == "" {
+		return content, nil
+	}
+	return fn(filename, content)
+}
+
+func formatNone(_, content string) (string, error) {
+	return content, nil
+}
+
+// maxBlankLines is the longest run of blank lines kept by formatText.  Some
+// languages use two blank lines to separate definitions, ie Python.
+const maxBlankLines = 2
+
+// markdownExts lists the extensions of Markdown files, whose trailing
+// whitespace is significant
+var markdownExts = map[string]bool{
+	".md":       true,
+	".markdown": true,
+}
+
+// formatText removes trailing whitespace from each line (except in Markdown,
+// where it marks line breaks), limits runs of blank lines to maxBlankLines,
+// and removes leading and trailing blank lines
+func formatText(filename, content string) (string, error) {
+	var (
+		lines    = strings.Split(content, "\n")
+		output   = make([]string, 0, len(lines))
+		markdown = markdownExts[path.Ext(filename)]
+		blanks   = maxBlankLines // Drops leading blank lines
+	)
+	for _, line := range lines {
+		if !markdown {
+			line = strings.TrimRight(line, " \t\r")
+		}
+		if strings.TrimSpace(line) == "" {
+			line = ""
+			if blanks >= maxBlankLines {
+				continue
+			}
+			blanks++
+		} else {
+			blanks = 0
+		}
+		output = append(output, line)
+	}
+	for len(output) > 0 && output[len(output)-1] == "" {
+		output = output[:len(output)-1]
+	}
+	formatted := strings.Join(output, "\n")
+	if strings.HasSuffix(content, "\n") {
+		formatted += "\n"
+	}
+	return formatted, nil
+}
+
+// formatGo applies gofmt.  Unlike goimports it never changes imports, so the
+// output doesn't depend on the packages available where the plugin runs.
+func formatGo(_, content string) (string, error) {
+	return GoFmt(content)
+}
+
+// formatGoImports applies goimports, which also adds missing imports by
+// searching the packages available where the plugin runs
+func formatGoImports(filename, content string) (string, error) {
+	b, err := imports.Process(filename, []byte(content), &imports.Options{
+		Comments:  true,
+		TabIndent: true,
+		TabWidth:  8,
+	})
+	if err != nil {
+		return "", goSourceError(content, err)
+	}
+	return string(b), nil
+}
+
+// goSourceError describes the first error in a list of Go syntax errors
+func goSourceError(content string, err error) error {
+	if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+		return sourceError(content, list[0].Pos.Line, list[0].Msg)
+	}
+	return err
+}
+
+// formatJSON re-indents JSON with two spaces, ending it with a single newline
+func formatJSON(_, content string) (string, error) {
+	// json.Indent keeps trailing whitespace, which would follow the newline
+	content = strings.TrimRightFunc(content, unicode.IsSpace)
+	buffer := &bytes.Buffer{}
+	if err := json.Indent(buffer, []byte(content), "", "  "); err != nil {
+		if syntaxErr, ok := err.(*json.SyntaxError); ok {
+			line := strings.Count(content[:syntaxErr.Offset], "\n") + 1
+			return "", sourceError(content, line, syntaxErr.Error())
+		}
+		return "", err
+	}
+	buffer.WriteString("\n")
+	return buffer.String(), nil
+}
+
+// formatYAML re-encodes each YAML document with two-space indentation,
+// preserving comments
+func formatYAML(_, content string) (string, error) {
+	var (
+		decoder = yaml.NewDecoder(strings.NewReader(content))
+		buffer  = &bytes.Buffer{}
+		encoder = yaml.NewEncoder(buffer)
+	)
+	encoder.SetIndent(2)
+
+	for {
+		node := &yaml.Node{}
+		err := decoder.Decode(node)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", errors.Wrap(err, "decoding YAML")
+		}
+		if err := encoder.Encode(node); err != nil {
+			return "", errors.Wrap(err, "encoding YAML")
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return "", errors.Wrap(err, "encoding YAML")
+	}
+	return buffer.String(), nil
+}
+
+// sourceError describes an error at the given line of content, quoting the
+// line rather than the whole content
+func sourceError(content string, line int, msg string) error {
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return errors.Errorf("line %d: %s", line, msg)
+	}
+	return errors.New(fmt.Sprintf("line %d: %s\n\t%s", line, msg, lines[line-1]))
+}
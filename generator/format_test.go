@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatOutput(t *testing.T) {
+	for _, c := range []struct {
+		format, filename, content, expected string
+		insertion                           bool
+	}{
+		{"", "foo.go", "package foo\nimport \"fmt\"\ntype   Foo struct{}\n", "package foo\n\nimport \"fmt\"\n\ntype Foo struct{}\n", false},
+		{"", "foo.go", "func   foo() {}  \n", "func   foo() {}\n", true},
+		{"", "foo.go", "package foo\nvar _ = proto.Marshal\n", "package foo\n\nvar _ = proto.Marshal\n", false},
+		{"gofmt", "foo.go", "package foo\nimport (\n\"gopkg.in/yaml.v3\"\n\"github.com/x/go-foo\"\n)\nvar _ = yaml.Marshal\nvar _ = foo.Foo\n",
+			"package foo\n\nimport (\n\t\"github.com/x/go-foo\"\n\t\"gopkg.in/yaml.v3\"\n)\n\nvar _ = yaml.Marshal\nvar _ = foo.Foo\n", false},
+		{"", "foo.json", `{"a":[1,2]}`, "{\n  \"a\": [\n    1,\n    2\n  ]\n}\n", false},
+		{"", "foo.yaml", "a:\n    - 1 # one\n", "a:\n  - 1 # one\n", false},
+		{"", "foo.md", "\n\n# Foo\n\n\n\nbar  \nbaz\n\n", "# Foo\n\n\nbar  \nbaz\n", false},
+		{"", "foo.py", "def f():  \n    pass\n\n\ndef g():\n    pass\n", "def f():\n    pass\n\n\ndef g():\n    pass\n", false},
+		{"", "foo.txt", "a\n\n\n\n\nb \n", "a\n\n\nb\n", false},
+		{"auto", "foo", "foo", "foo", false},
+		{"none", "foo.json", `{"a":1}  `, `{"a":1}  `, false},
+		{"json", "foo.txt", `{}`, "{}\n", false},
+		{"", "foo.json", "{\"a\": 1}\n\n", "{\n  \"a\": 1\n}\n", false},
+		{"", "foo.json", " \n", " \n", false},
+		{"", "foo.go", "\n\n", "\n\n", false},
+		{"goimports", "foo.go", " ", " ", false},
+		{"", "foo.yaml", "\n", "\n", false},
+		{"", "foo.txt", "  \n", "  \n", false},
+	} {
+		actual, err := formatOutput(c.format, c.filename, c.content, c.insertion)
+		if err != nil {
+			t.Errorf("%s: formatting: %s", c.filename, err)
+			continue
+		}
+		if actual != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.filename, c.expected, actual)
+		}
+	}
+}
+
+func TestFormatOutputErrors(t *testing.T) {
+	for _, c := range []struct {
+		filename, content, line string
+	}{
+		{"foo.go", "package foo\n\nfunc {\n", "line 3:"},
+		{"foo.json", "{\n  \"a\": 1,\n}\n", "line 3:"},
+		{"foo.yaml", "a: 1\nb: [\n", "line 2:"},
+	} {
+		_, err := formatOutput("", c.filename, c.content, false)
+		if err == nil {
+			t.Errorf("%s: expected error", c.filename)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.line) {
+			t.Errorf("%s: expected error containing %q, got %q", c.filename, c.line, err)
+		}
+		if strings.Contains(err.Error(), c.content) {
+			t.Errorf("%s: expected error not to contain the whole content, got %q", c.filename, err)
+		}
+	}
+
+	if _, err := formatOutput("unknown", "foo", "", false); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}
@@ -0,0 +1,176 @@
+package generator
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	// frontMatterStart opens the front matter block at the top of a template.
+	// It's distinct from a plain `---` line so templates of YAML documents or
+	// Markdown with its own front matter are left alone.
+	frontMatterStart = "---template"
+
+	// frontMatterEnd closes the front matter block
+	frontMatterEnd = "---"
+)
+
+var (
+	// frontMatterTargets maps front matter `expand` values to the Data methods
+	// returning the values a template is expanded over
+	frontMatterTargets = map[string]string{
+		"data":    "",
+		"file":    "Files",
+		"message": "Messages",
+		"enum":    "Enums",
+		"service": "Services",
+	}
+)
+
+// frontMatter configures the output of a template.  It's parsed from an
+// optional YAML block at the top of the template, opened by a `---template`
+// line and closed by a `---` line:
+//
+//	---template
+//	path: "{{.File.Package}}/{{.Name}}.md"
+//	expand: message
+//	filter: [visible, notnested]
+//	format: none
+//	delims: ["[[", "]]"]
+//	skip_empty: true
+//	---
+//
+// Unset values fall back to the template's name and the plugin's parameters.
+type frontMatter struct {
+	Path      string   `yaml:"path"`       // Output path, evaluated as a template
+	Expand    string   `yaml:"expand"`     // One of data, file, message, enum or service
+	Filter    []string `yaml:"filter"`     // Filters applied to expanded values, ie visible
+	Format    string   `yaml:"format"`     // One of auto, none, text, gofmt, goimports, json or yaml
+	Delims    []string `yaml:"delims"`     // Left and right template action delimiters
+	SkipEmpty *bool    `yaml:"skip_empty"` // Don't write the output if it only contains whitespace
+}
+
+// parseFrontMatter separates a template's front matter from its body.  The
+// front matter is replaced by a template comment spanning the same number of
+// lines, so line numbers in template errors match the template file.
+func parseFrontMatter(b []byte) (*frontMatter, string, error) {
+	fm := &frontMatter{}
+
+	lines := strings.SplitAfter(string(b), "\n")
+	if len(lines) == 0 || strings.TrimRight(lines[0], "\r\n") != frontMatterStart {
+		return fm, string(b), nil
+	}
+
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], "\r\n") == frontMatterEnd {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, "", errors.New("front matter is not terminated")
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(strings.Join(lines[1:end], "")))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(fm); err != nil && err != io.EOF {
+		return nil, "", errors.Wrap(err, "decoding front matter")
+	}
+	if err := fm.validate(); err != nil {
+		return nil, "", errors.Wrap(err, "validating front matter")
+	}
+
+	left, right := "{{", "}}"
+	if len(fm.Delims) == 2 {
+		left, right = fm.Delims[0], fm.Delims[1]
+	}
+
+	body := &bytes.Buffer{}
+	body.WriteString(left + "/*")
+	body.WriteString(strings.Repeat("\n", end+1))
+	body.WriteString("*/" + right)
+	body.WriteString(strings.Join(lines[end+1:], ""))
+	return fm, body.String(), nil
+}
+
+func (fm *frontMatter) validate() error {
+	if _, found := frontMatterTargets[fm.Expand]; fm.Expand != "" && !found {
+		return errors.Errorf("unknown expand value %q", fm.Expand)
+	}
+	for _, filter := range fm.Filter {
+		if _, found := expansionFilters[strings.ToLower(filter)]; !found {
+			return errors.Errorf("unknown filter %q", filter)
+		}
+	}
+	if _, found := formatters[fm.Format]; fm.Format != "" && fm.Format != autoFormat && !found {
+		return errors.Errorf("unknown format %q", fm.Format)
+	}
+	if len(fm.Delims) != 0 && len(fm.Delims) != 2 {
+		return errors.New("delims must contain a left and right delimiter")
+	}
+	return nil
+}
+
+// apply configures the template's output, given its output path derived from
+// the template's name
+func (fm *frontMatter) apply(info *fileInfo, outPath string) {
+	if len(fm.Delims) == 2 {
+		info.delims = [2]string{fm.Delims[0], fm.Delims[1]}
+	}
+	if fm.SkipEmpty != nil {
+		info.skipEmpty = *fm.SkipEmpty
+	}
+	if fm.Format != "" {
+		info.format = fm.Format
+	}
+
+	if fm.Expand == "" {
+		if fm.Path == "" {
+			info.outPath, info.expansion = parseExpansion(outPath)
+			return
+		}
+
+		// Explicit paths aren't subject to the template name's suffix rules,
+		// but are still expanded over files when templated
+		info.outPath = fm.Path
+		left := "{{"
+		if len(fm.Delims) == 2 {
+			left = fm.Delims[0]
+		}
+		if strings.Contains(fm.Path, left) {
+			info.expansion = &expansion{target: "Files", filters: []string{"ToGenerate"}}
+		}
+		return
+	}
+
+	info.outPath = outPath
+	if fm.Path != "" {
+		info.outPath = fm.Path
+	}
+	target := frontMatterTargets[fm.Expand]
+	if target == "" {
+		return
+	}
+
+	var (
+		filters = []string{}
+		all     = false
+	)
+	for _, filter := range fm.Filter {
+		method := expansionFilters[strings.ToLower(filter)]
+		if method == "" {
+			all = true
+			continue
+		}
+		filters = append(filters, method)
+	}
+	if !all {
+		filters = append([]string{"ToGenerate"}, filters...)
+	}
+	info.expansion = &expansion{target: target, filters: filters}
+}
@@ -0,0 +1,196 @@
+package generator
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// openLayers returns a file system merging the given template roots, along
+// with the root to walk within it.  Files in later roots shadow files with the
+// same relative path in earlier roots.  Roots are resolved in fsys, or using
+// openTemplates when fsys is nil.
+func openLayers(fsys fs.FS, roots []string) (fs.FS, string, error) {
+	if len(roots) == 1 {
+		if fsys != nil {
+			return fsys, roots[0], nil
+		}
+		return openTemplates(roots[0])
+	}
+
+	layers := make(overlayFS, 0, len(roots))
+	for _, root := range roots {
+		layer, layerRoot := fsys, root
+		if fsys == nil {
+			var err error
+			layer, layerRoot, err = openTemplates(root)
+			if err != nil {
+				return nil, "", errors.Wrapf(err, "opening templates %s", root)
+			}
+		}
+
+		info, err := fs.Stat(layer, layerRoot)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "opening templates %s", root)
+		}
+		if !info.IsDir() {
+			return nil, "", errors.Errorf("layered templates %s must be a directory or bundle", root)
+		}
+		layer, err = fs.Sub(layer, layerRoot)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "opening templates %s", root)
+		}
+		layers = append(layers, layer)
+	}
+	return layers, ".", nil
+}
+
+// openTemplates returns a file system containing the templates at the given
+// path, along with the root to walk within it.  The path may be a directory,
+// a single template file, or a `.zip`, `.tar.gz` or `.tgz` template bundle.
+func openTemplates(p string) (fs.FS, string, error) {
+	switch {
+	case strings.HasSuffix(p, ".zip"):
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "reading zip bundle")
+		}
+		r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			return nil, "", errors.Wrap(err, "opening zip bundle")
+		}
+		return r, ".", nil
+
+	case strings.HasSuffix(p, ".tar.gz"), strings.HasSuffix(p, ".tgz"):
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "opening tar bundle")
+		}
+		defer f.Close()
+
+		r, err := tarToZip(f)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "reading tar bundle")
+		}
+		return r, ".", nil
+	}
+
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, "", err
+	}
+	if !info.IsDir() {
+		return os.DirFS(filepath.Dir(p)), info.Name(), nil
+	}
+	return os.DirFS(p), ".", nil
+}
+
+// tarToZip repacks a gzipped tar archive as an in-memory zip archive, so both
+// bundle formats share zip.Reader's fs.FS implementation
+func tarToZip(r io.Reader) (*zip.Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "decompressing")
+	}
+
+	var (
+		tr  = tar.NewReader(gz)
+		buf = &bytes.Buffer{}
+		zw  = zip.NewWriter(buf)
+	)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading entry")
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		w, err := zw.Create(path.Clean(header.Name))
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating entry %s", header.Name)
+		}
+		if _, err := io.Copy(w, tr); err != nil {
+			return nil, errors.Wrapf(err, "copying entry %s", header.Name)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, errors.Wrap(err, "closing archive")
+	}
+
+	return zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+}
+
+// relPath returns the path of name relative to the walked root
+func relPath(root, name string) string {
+	switch {
+	case root == ".":
+		return name
+	case root == name:
+		return path.Base(name)
+	default:
+		return strings.TrimPrefix(name, root+"/")
+	}
+}
+
+// overlayFS merges file systems, with files in later layers shadowing files
+// with the same path in earlier layers
+type overlayFS []fs.FS
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	for i := len(o) - 1; i >= 0; i-- {
+		f, err := o[i].Open(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		return f, err
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir lists the entries of the named directory in every layer.  Entries
+// from later layers replace entries with the same name in earlier layers.
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	var (
+		merged = map[string]fs.DirEntry{}
+		found  = false
+	)
+	for _, layer := range o {
+		entries, err := fs.ReadDir(layer, name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, entry := range entries {
+			merged[entry.Name()] = entry
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(merged))
+	for _, entry := range merged {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
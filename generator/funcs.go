@@ -0,0 +1,244 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/scanner"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/iancoleman/strcase"
+)
+
+// Funcs is the template.FuncMap used for template execution.  Templates can
+// also call `exec`, which executes the named template and returns its output
+// as a string, `option`, which returns the value of a custom option (see
+// data.Data.Option), as well as the functions described by `output`.
+var Funcs = template.FuncMap{
+	"gofmt":      GoFmt,
+	"uppercamel": UpperCamel,
+	"upper":      Upper,
+	"lowercamel": LowerCamel,
+	"lower":      Lower,
+	"replace":    Replace,
+	"join":       strings.Join,
+	"split":      strings.Split,
+	"dict":       Dict,
+	"merge":      Merge,
+	"base64":     base64.StdEncoding.EncodeToString,
+	"base":       path.Base,
+	"dir":        path.Dir,
+	"ext":        path.Ext,
+	"trimext":    TrimExt,
+
+	"gopackage": GoPackage,
+	"goimport":  GoImport,
+	"goimports": GoImports,
+	"import":    Import,
+	"imports":   Imports,
+
+	"sortbyname":     SortByName,
+	"sortbynumber":   SortByNumber,
+	"sortbyfullname": SortByFullName,
+}
+
+// GoFmt applies gofmt to the string, reporting errors with the offending line
+func GoFmt(s string) (string, error) {
+	b, err := format.Source([]byte(s))
+	if err != nil {
+		if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+			return "", sourceError(s, list[0].Pos.Line, list[0].Msg)
+		}
+		return "", err
+	}
+	return string(b), nil
+}
+
+// UpperCamel converts a snake_case string into CamelCase
+func UpperCamel(s string) string {
+	return strcase.ToCamel(s)
+}
+
+// Upper converts a string to uppercase
+func Upper(s string) string {
+	return strings.ToUpper(s)
+}
+
+// LowerCamel converts a snake_case string into camelCase
+func LowerCamel(s string) string {
+	if len(s) == 0 {
+		return s
+	}
+	b := []byte(strcase.ToCamel(s))
+	b[0] = bytes.ToLower(b[0:1])[0]
+	return string(b)
+}
+
+// Lower converts a string to lowercase
+func Lower(s string) string {
+	return strings.ToLower(s)
+}
+
+// Replace wraps strings.Replace for better use with template pipes
+// Example: "foo" | replace "f" "p" | replace "p" "m"
+func Replace(old, new, s string) string {
+	return strings.Replace(s, old, new, -1)
+}
+
+// TrimExt removes the extension from a slash-separated path
+// Example: "foo/bar.proto" | trimext -> "foo/bar"
+func TrimExt(s string) string {
+	return strings.TrimSuffix(s, path.Ext(s))
+}
+
+// Dict converts a set of name/value pairs into a map
+//
+// Example:
+//
+//   {{ $map := dict "Foo" 1 "Bar" 2 }}
+//   {{ $map.Foo }} -> 1
+//   {{ $map.Bar }} -> 2
+//
+func Dict(values ...interface{}) (map[string]interface{}, error) {
+	if len(values)%2 != 0 {
+		return nil, errors.New("invalid dict call")
+	}
+	dict := make(map[string]interface{}, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		key, ok := values[i].(string)
+		if !ok {
+			return nil, errors.New("dict keys must be strings")
+		}
+		dict[key] = values[i+1]
+	}
+	return dict, nil
+}
+
+// Merge returns a copy of the given map with the given set of name/value pairs
+// assigned
+//
+// Example:
+//
+//   {{ $map := dict "Foo" 1 "Bar" 2 }}
+//   {{ $map.Foo }} -> 1
+//   {{ $map.Bar }} -> 2
+//   {{ (merge $map "Foo" 3).Foo }} -> 3
+//   {{ (merge $map "Foo" 3).Bar }} -> 2
+//   {{ $map.Foo }} -> 1
+//
+func Merge(source map[string]interface{}, values ...interface{}) (map[string]interface{}, error) {
+	if len(values)%2 != 0 {
+		return nil, errors.New("invalid dict call")
+	}
+	dict := make(map[string]interface{}, len(source)+(len(values)/2))
+	for k, v := range source {
+		dict[k] = v
+	}
+
+	for i := 0; i < len(values); i += 2 {
+		key, ok := values[i].(string)
+		if !ok {
+			return nil, errors.New("dict keys must be strings")
+		}
+		dict[key] = values[i+1]
+	}
+
+	return dict, nil
+}
+
+// SortByName returns a copy of a slice of files, messages, fields, etc sorted
+// by their Name
+//
+// Example:
+//
+//   {{ range sortbyname .Messages }}...{{ end }}
+//
+func SortByName(values interface{}) (interface{}, error) {
+	return sortSlice(values, "Name", func(v reflect.Value) (interface{}, error) {
+		if f := v.FieldByName("Name"); f.IsValid() && f.Kind() == reflect.String {
+			return f.String(), nil
+		}
+		return nil, fmt.Errorf("%s doesn't have a Name", v.Type())
+	})
+}
+
+// SortByNumber returns a copy of a slice of fields, enum values or extensions
+// sorted by their Number
+//
+// Example:
+//
+//   {{ range sortbynumber .Fields }}...{{ end }}
+//
+func SortByNumber(values interface{}) (interface{}, error) {
+	return sortSlice(values, "Number", func(v reflect.Value) (interface{}, error) {
+		if f := v.FieldByName("Number"); f.IsValid() && f.Kind() == reflect.Int32 {
+			return f.Int(), nil
+		}
+		return nil, fmt.Errorf("%s doesn't have a Number", v.Type())
+	})
+}
+
+// SortByFullName returns a copy of a slice of messages, fields, enums, etc
+// sorted by their fully-qualified name
+//
+// Example:
+//
+//   {{ range sortbyfullname .Data.Enums }}...{{ end }}
+//
+func SortByFullName(values interface{}) (interface{}, error) {
+	return sortSlice(values, "FullName", func(v reflect.Value) (interface{}, error) {
+		if m := v.MethodByName("FullName"); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() == 1 {
+			if s, ok := m.Call(nil)[0].Interface().(string); ok {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("%s doesn't have a FullName", v.Type())
+	})
+}
+
+// sortSlice returns a stably sorted copy of a slice, using key to extract the
+// string or integer each element is sorted by
+func sortSlice(values interface{}, name string, key func(reflect.Value) (interface{}, error)) (interface{}, error) {
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("can't sort %T by %s", values, name)
+	}
+
+	keys := make([]interface{}, v.Len())
+	for i := range keys {
+		elem := v.Index(i)
+		for elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface {
+			elem = elem.Elem()
+		}
+		k, err := key(elem)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = k
+	}
+
+	indices := make([]int, v.Len())
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		switch a := keys[indices[i]].(type) {
+		case string:
+			return a < keys[indices[j]].(string)
+		default:
+			return a.(int64) < keys[indices[j]].(int64)
+		}
+	})
+
+	sorted := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+	for i, idx := range indices {
+		sorted.Index(i).Set(v.Index(idx))
+	}
+	return sorted.Interface(), nil
+}
@@ -0,0 +1,429 @@
+// Package generator renders templates describing a protoc
+// CodeGeneratorRequest.  It implements protoc-gen-template, and can be used to
+// build plugins providing additional template functions:
+//
+//	res, err := generator.Generate(req, generator.Options{
+//		Funcs: template.FuncMap{"tenant": tenantName},
+//	})
+package generator
+
+import (
+	"bytes"
+	"io/fs"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/golang/protobuf/proto"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/kerinin/protoc-gen-template/data"
+	"github.com/pkg/errors"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// DefaultTemplate is the template path used when neither Options nor the
+// request's parameter specify one
+const DefaultTemplate = "."
+
+// SupportedFeatures are the optional protoc features advertised in responses
+const SupportedFeatures = uint64(plugin.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL |
+	plugin.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS)
+
+// MinimumEdition and MaximumEdition are the range of editions advertised in
+// responses
+const (
+	MinimumEdition = descriptor.Edition_EDITION_PROTO2
+	MaximumEdition = descriptor.Edition_EDITION_2023
+)
+
+// Options configures a call to Generate
+type Options struct {
+	// Templates is the template path, overriding the `template` parameter.
+	// It's relative to FS when set.
+	Templates string
+
+	// Layers are template paths layered over the template path.  Files in
+	// later layers shadow files with the same relative path in earlier ones.
+	Layers []string
+
+	// FS is the file system templates are loaded from, ie an embed.FS.  When
+	// nil, templates are loaded from the OS file system, or from a `.zip`,
+	// `.tar.gz` or `.tgz` bundle when the template path names one.
+	FS fs.FS
+
+	// Params are merged over the key=value pairs parsed from the request's
+	// parameter
+	Params map[string]string
+
+	// Funcs are template functions available in addition to Funcs.  Functions
+	// with the same name as those in Funcs replace them.
+	Funcs template.FuncMap
+
+	// NoCapture ignores the `capture` parameter, ie when rendering a request
+	// which was itself captured
+	NoCapture bool
+}
+
+// Generate executes the templates described by the request and options,
+// returning the response to send to protoc
+func Generate(req *plugin.CodeGeneratorRequest, opts Options) (*plugin.CodeGeneratorResponse, error) {
+	g := &generator{
+		funcs: make(template.FuncMap, len(Funcs)+len(opts.Funcs)),
+	}
+	for k, v := range Funcs {
+		g.funcs[k] = v
+	}
+	for k, v := range opts.Funcs {
+		g.funcs[k] = v
+	}
+
+	files, err := g.generateFiles(req, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin.CodeGeneratorResponse{
+		File:              files,
+		SupportedFeatures: proto.Uint64(SupportedFeatures),
+		MinimumEdition:    proto.Int32(int32(MinimumEdition)),
+		MaximumEdition:    proto.Int32(int32(MaximumEdition)),
+	}, nil
+}
+
+// generator holds the state of a single Generate call
+type generator struct {
+	tmpl   *template.Template
+	funcs  template.FuncMap
+	fsys   fs.FS
+	params map[string]string
+	data   *data.Data
+}
+
+type fileInfo struct {
+	inPath       string
+	outPath      string
+	templateName string
+	expansion    *expansion // Non-nil when outPath is a template evaluated once per expanded value
+	skipEmpty    bool       // Don't write the output if it only contains whitespace
+	format       string     // Format applied to the output, selected by extension if empty
+	delims       [2]string  // Template action delimiters, defaults are used if empty
+}
+
+func (g *generator) generateFiles(req *plugin.CodeGeneratorRequest, opts Options) ([]*plugin.CodeGeneratorResponse_File, error) {
+
+	if len(req.FileToGenerate) == 0 {
+		return nil, errors.New("no files to generate")
+	}
+
+	params, values, err := parseParameter(req.GetParameter())
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing parameter")
+	}
+	for k, v := range opts.Params {
+		params[k] = v
+		values[k] = []string{v}
+	}
+	if path := params[captureParam]; path != "" && !opts.NoCapture {
+		if err := captureRequest(req, path); err != nil {
+			return nil, errors.Wrap(err, "capturing request")
+		}
+	}
+	templatePaths := values[templateParam]
+	if opts.Templates != "" {
+		templatePaths = []string{opts.Templates}
+	}
+	if len(templatePaths) == 0 {
+		templatePaths = []string{DefaultTemplate}
+	}
+	templatePaths = append(templatePaths, opts.Layers...)
+	params[templateParam] = templatePaths[0]
+	g.params = params
+
+	skipEmpty := false
+	if v, found := params[skipEmptyParam]; found {
+		skipEmpty, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s parameter", skipEmptyParam)
+		}
+	}
+
+	// NOTE: Functions must be defined before templates are parsed, but `exec`,
+	// `option` and the output functions need access to the parsed templates,
+	// the request's data and the output being generated.  They're bound to
+	// the generator, and the output functions are re-bound before each
+	// template is executed.
+	g.tmpl = template.New("").Funcs(g.templateFuncs())
+
+	var (
+		templateFiles = []fileInfo{}
+		copyFiles     = []fileInfo{}
+	)
+	format := params[formatParam]
+	if _, found := formatters[format]; format != "" && format != autoFormat && !found {
+		return nil, errors.Errorf("unknown %s parameter %q", formatParam, format)
+	}
+
+	var root string
+	g.fsys, root, err = openLayers(opts.FS, templatePaths)
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening templates %s", strings.Join(templatePaths, ", "))
+	}
+
+	err = fs.WalkDir(g.fsys, root, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.Wrapf(err, "walking at path %s", filename)
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		// The path relative to the compile output directory
+		outPath := relPath(root, filename)
+
+		switch {
+		case strings.HasSuffix(entry.Name(), `.associated.tmpl`):
+			b, err := fs.ReadFile(g.fsys, filename)
+			if err != nil {
+				return errors.Wrapf(err, "reading file %s", filename)
+			}
+			fm, body, err := parseFrontMatter(b)
+			if err != nil {
+				return errors.Wrapf(err, "parsing front matter %s", filename)
+			}
+
+			templateName := strings.TrimSuffix(outPath, `.associated.tmpl`)
+
+			// NOTE: Only delimiters apply to associated templates, they
+			// don't produce any output to configure.
+			info := fileInfo{}
+			fm.apply(&info, "")
+
+			_, err = g.tmpl.New(templateName).Delims(info.delims[0], info.delims[1]).Parse(body)
+			if err != nil {
+				return errors.Wrapf(err, "parsing template %s", filename)
+			}
+
+		case strings.HasSuffix(entry.Name(), `.tmpl`):
+			b, err := fs.ReadFile(g.fsys, filename)
+			if err != nil {
+				return errors.Wrapf(err, "reading file %s", filename)
+			}
+			fm, body, err := parseFrontMatter(b)
+			if err != nil {
+				return errors.Wrapf(err, "parsing front matter %s", filename)
+			}
+
+			info := fileInfo{
+				inPath:       filename,
+				templateName: strings.TrimSuffix(outPath, `.tmpl`),
+				skipEmpty:    skipEmpty,
+				format:       format,
+			}
+			fm.apply(&info, strings.TrimSuffix(outPath, `.tmpl`))
+
+			_, err = g.tmpl.New(info.templateName).Delims(info.delims[0], info.delims[1]).Parse(body)
+			if err != nil {
+				return errors.Wrapf(err, "parsing template %s", filename)
+			}
+			templateFiles = append(templateFiles, info)
+
+		default:
+			copyFiles = append(copyFiles, fileInfo{
+				inPath:  filename,
+				outPath: outPath,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "walking input")
+	}
+
+	config := data.Config{}
+	if tags, found := values[commentTagParam]; found {
+		config.CommentTags = make([]string, 0, len(tags))
+		for _, tag := range tags {
+			if tag != "" {
+				config.CommentTags = append(config.CommentTags, tag)
+			}
+		}
+	}
+	d, err := data.NewWithConfig(req, config)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading request")
+	}
+	d.Params = params
+	g.data = d
+
+	var (
+		files   = make([]*plugin.CodeGeneratorResponse_File, 0, len(templateFiles)+len(copyFiles))
+		sources = fileSources{}
+	)
+	for _, f := range templateFiles {
+		if f.expansion == nil {
+			if f.outPath, err = cleanOutputPath(f.outPath); err != nil {
+				return nil, errors.Wrapf(err, "generating file %s", f.inPath)
+			}
+			generated, err := g.generateFile(f, d)
+			if err != nil {
+				return nil, errors.Wrapf(err, "generating file %s", f.inPath)
+			}
+			if err := sources.add(f.inPath, generated...); err != nil {
+				return nil, err
+			}
+			files = append(files, generated...)
+			continue
+		}
+
+		// Expanded templates are executed once for each value, with dot set to
+		// the value when rendering both the path and the template.
+		values, err := f.expansion.values(d)
+		if err != nil {
+			return nil, errors.Wrapf(err, "expanding template %s", f.inPath)
+		}
+		for _, v := range values {
+			outPath, err := g.renderPath(f.outPath, f.delims, v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "rendering path %s for %s", f.outPath, v)
+			}
+
+			expanded := f
+			expanded.outPath = outPath
+			generated, err := g.generateFile(expanded, v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "generating file %s for %s", f.inPath, v)
+			}
+			if err := sources.add(f.inPath, generated...); err != nil {
+				return nil, err
+			}
+			files = append(files, generated...)
+		}
+	}
+
+	for _, f := range copyFiles {
+		file, err := g.copyFile(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "copying file %s", f.inPath)
+		}
+		if err := sources.add(f.inPath, file); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+func (g *generator) generateFile(f fileInfo, d interface{}) ([]*plugin.CodeGeneratorResponse_File, error) {
+	out := &output{}
+	g.tmpl.Funcs(out.funcs())
+
+	buffer := &bytes.Buffer{}
+	err := g.tmpl.ExecuteTemplate(buffer, f.templateName, d)
+	if err != nil {
+		return nil, errors.Wrap(err, "executing template")
+	}
+
+	// Emitted files are formatted by their own extension, as the template's
+	// format describes its own output
+	for _, emitted := range out.emitted {
+		content, err := formatOutput(g.params[formatParam], emitted.GetName(), applyImports(emitted.GetName(), g.params, emitted.GetContent()), false)
+		if err != nil {
+			return nil, errors.Wrapf(err, "formatting emitted file %s", emitted.GetName())
+		}
+		emitted.Content = &content
+	}
+
+	content := applyImports(f.outPath, g.params, buffer.String())
+	if out.skip || (f.skipEmpty && strings.TrimSpace(content) == "") {
+		return out.emitted, nil
+	}
+
+	content, err = formatOutput(f.format, f.outPath, content, out.insertionPoint != "")
+	if err != nil {
+		return nil, errors.Wrap(err, "formatting output")
+	}
+
+	file := &plugin.CodeGeneratorResponse_File{
+		Name:    &f.outPath,
+		Content: &content,
+	}
+	if out.insertionPoint != "" {
+		file.InsertionPoint = &out.insertionPoint
+	}
+	return append([]*plugin.CodeGeneratorResponse_File{file}, out.emitted...), nil
+}
+
+// fileSources maps output file names to the input path producing them
+type fileSources map[string]string
+
+// add records the input path producing each file, failing if another input
+// already produced a file with the same name.  Insertions into other files
+// may share names.
+func (s fileSources) add(inPath string, files ...*plugin.CodeGeneratorResponse_File) error {
+	for _, file := range files {
+		if file.InsertionPoint != nil {
+			continue
+		}
+		name := file.GetName()
+		if source, found := s[name]; found {
+			return errors.Errorf("file %s generated by both %s and %s", name, source, inPath)
+		}
+		s[name] = inPath
+	}
+	return nil
+}
+
+// templateFuncs returns the functions available to templates and templated
+// output paths
+func (g *generator) templateFuncs() template.FuncMap {
+	funcs := template.FuncMap{}
+	for k, v := range g.funcs {
+		funcs[k] = v
+	}
+	funcs["exec"] = g.exec
+	funcs["option"] = g.option
+	for k, v := range (&output{}).funcs() {
+		funcs[k] = v
+	}
+	return funcs
+}
+
+// renderPath evaluates a templated output path with dot set to d
+func (g *generator) renderPath(outPath string, delims [2]string, d interface{}) (string, error) {
+	t, err := template.New(outPath).Funcs(g.templateFuncs()).Delims(delims[0], delims[1]).Parse(outPath)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing path")
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := t.Execute(buffer, d); err != nil {
+		return "", errors.Wrap(err, "executing path")
+	}
+	return cleanOutputPath(buffer.String())
+}
+
+func (g *generator) copyFile(f fileInfo) (*plugin.CodeGeneratorResponse_File, error) {
+	b, err := fs.ReadFile(g.fsys, f.inPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading file %s", f.inPath)
+	}
+	s := string(b)
+
+	return &plugin.CodeGeneratorResponse_File{
+		Name:    &f.outPath,
+		Content: &s,
+	}, nil
+}
+
+// option returns the value of the named custom option set on v
+func (g *generator) option(v interface{}, name string) (interface{}, error) {
+	return g.data.Option(v, name)
+}
+
+// exec executes the named template, returning its output as a string
+func (g *generator) exec(name string, data interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	err := g.tmpl.ExecuteTemplate(buf, name, data)
+	return buf.String(), err
+}
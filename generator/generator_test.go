@@ -0,0 +1,963 @@
+package generator
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+
+	"github.com/golang/protobuf/proto"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"google.golang.org/protobuf/encoding/protowire"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func testRequest(t *testing.T, templates map[string]string) *plugin.CodeGeneratorRequest {
+	b, err := ioutil.ReadFile("../data/testdata/dump.pb")
+	if err != nil {
+		t.Fatalf("reading request: %s", err)
+	}
+	req := &plugin.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(b, req); err != nil {
+		t.Fatalf("unmarshaling request: %s", err)
+	}
+
+	dir, err := ioutil.TempDir("", "protoc-gen-template")
+	if err != nil {
+		t.Fatalf("creating template dir: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for name, content := range templates {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatalf("creating template dir: %s", err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatalf("writing template: %s", err)
+		}
+	}
+	req.Parameter = &dir
+
+	return req
+}
+
+func testGenerate(t *testing.T, req *plugin.CodeGeneratorRequest) map[string]string {
+	res, err := Generate(req, Options{})
+	if err != nil {
+		t.Fatalf("generating files: %s", err)
+	}
+	return responseOutputs(res)
+}
+
+func responseOutputs(res *plugin.CodeGeneratorResponse) map[string]string {
+	outputs := make(map[string]string, len(res.File))
+	for _, f := range res.File {
+		outputs[f.GetName()] = f.GetContent()
+	}
+	return outputs
+}
+
+func testOutputs(t *testing.T, expected, actual map[string]string) {
+	if len(expected) != len(actual) {
+		t.Errorf("expected %d files, got %d: %v", len(expected), len(actual), actual)
+	}
+	for name, content := range expected {
+		if actual[name] != content {
+			t.Errorf("%s: expected %q, got %q", name, content, actual[name])
+		}
+	}
+}
+
+func TestGeneratePerFile(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"{{.Package}}/{{.Name | base | trimext}}.txt.tmpl": "{{.Package}} {{len .Data.Files}}",
+		"all.txt.tmpl": "{{len .Files}}",
+	})
+
+	testOutputs(t, map[string]string{
+		"all.txt":           "4",
+		"testv2/testv2.txt": "testv2 4",
+		"testv3/testv3.txt": "testv3 4",
+	}, testGenerate(t, req))
+}
+
+func TestGeneratePathFuncs(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		`{{exec "dir" .}}/{{.Name | base | trimext}}.txt.tmpl`: "{{.Package}}",
+		"dir.associated.tmpl": "{{.Package | upper}}",
+	})
+
+	testOutputs(t, map[string]string{
+		"TESTV2/testv2.txt": "testv2",
+		"TESTV3/testv3.txt": "testv3",
+	}, testGenerate(t, req))
+}
+
+func TestGeneratePerEntity(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"{{.File.Package}}/{{.Name}}.txt.messages.notnested.tmpl": "{{.}}",
+		"{{.File.Package}}/{{.Name}}.txt.services.tmpl":           "{{len .Methods}}",
+	})
+
+	testOutputs(t, map[string]string{
+		"testv2/Message.txt":      ".testv2.Message",
+		"testv2/OtherMessage.txt": ".testv2.OtherMessage",
+		"testv3/Message.txt":      ".testv3.Message",
+		"testv3/OtherMessage.txt": ".testv3.OtherMessage",
+		"testv2/Service.txt":      "1",
+		"testv2/OtherService.txt": "0",
+		"testv3/Service.txt":      "1",
+		"testv3/OtherService.txt": "0",
+	}, testGenerate(t, req))
+}
+
+func TestGenerateParams(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"params.txt.tmpl": "{{.Params.lang}} {{.Params.strict}}",
+	})
+	parameter := "template=" + req.GetParameter() + ",lang=ts,strict=true"
+	req.Parameter = &parameter
+
+	testOutputs(t, map[string]string{
+		"params.txt": "ts true",
+	}, testGenerate(t, req))
+}
+
+func TestParseParameter(t *testing.T) {
+	params, values, err := parseParameter("templates,lang=ts,,module=foo=bar,template=c++,lang=py")
+	if err != nil {
+		t.Fatalf("parsing parameter: %s", err)
+	}
+	testOutputs(t, map[string]string{
+		"template": "c++",
+		"lang":     "py",
+		"module":   "foo=bar",
+	}, params)
+	if got := strings.Join(values["template"], " "); got != "templates c++" {
+		t.Errorf("expected every template value, got %q", got)
+	}
+
+	if _, _, err := parseParameter("a,b"); err == nil {
+		t.Errorf("expected error for multiple template paths")
+	}
+}
+
+func TestGenerateInsertion(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"{{.Name | trimext}}.pb.go.tmpl": `{{ insertion "package_scope" }}// {{.Package}}`,
+	})
+
+	res, err := Generate(req, Options{})
+	if err != nil {
+		t.Fatalf("generating files: %s", err)
+	}
+	if len(res.File) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(res.File))
+	}
+	for _, f := range res.File {
+		if f.GetInsertionPoint() != "package_scope" {
+			t.Errorf("%s: expected insertion point package_scope, got %q", f.GetName(), f.GetInsertionPoint())
+		}
+	}
+}
+
+func TestGenerateEmit(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"index.txt.tmpl": `{{ range .Files.ToGenerate }}{{ emit (printf "%s.txt" .Package) .Package }}{{ end }}index`,
+		"types.go.tmpl":  "---template\nformat: gofmt\n---\n{{ emit \"schema.json\" `{\"a\":1}` }}package   types\n",
+	})
+
+	testOutputs(t, map[string]string{
+		"index.txt":   "index",
+		"testv2.txt":  "testv2",
+		"testv3.txt":  "testv3",
+		"types.go":    "package types\n",
+		"schema.json": "{\n  \"a\": 1\n}\n",
+	}, testGenerate(t, req))
+}
+
+func TestGenerateOutsideOutput(t *testing.T) {
+	for _, tmpl := range []string{
+		"---template\npath: \"../../{{.Package}}.txt\"\n---\n",
+		"---template\npath: /tmp/out.txt\n---\n",
+		`{{ emit "../out.txt" "" }}`,
+	} {
+		req := testRequest(t, map[string]string{"out.txt.tmpl": tmpl})
+		if _, err := Generate(req, Options{}); err == nil || !strings.Contains(err.Error(), "outside the output directory") {
+			t.Errorf("%q: expected error for path outside the output directory, got %v", tmpl, err)
+		}
+	}
+}
+
+func TestGenerateCollision(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"index.txt.tmpl": `{{ emit "other.txt" "" }}`,
+		"other.txt.tmpl": ``,
+	})
+
+	if _, err := Generate(req, Options{}); err == nil {
+		t.Errorf("expected error for colliding file names")
+	}
+}
+
+func TestGenerateSkip(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"{{.File.Package}}/{{.Name}}.txt.messages.notnested.tmpl": `{{ if not .Fields }}{{ skip }}{{ end }}{{ len .Fields }}`,
+		"empty.txt.tmpl": "---template\nformat: none\n---\n  \n",
+	})
+
+	testOutputs(t, map[string]string{
+		"testv2/Message.txt": "7",
+		"testv3/Message.txt": "7",
+		"empty.txt":          "  \n",
+	}, testGenerate(t, req))
+
+	parameter := "skip_empty=true," + req.GetParameter()
+	req.Parameter = &parameter
+	testOutputs(t, map[string]string{
+		"testv2/Message.txt": "7",
+		"testv3/Message.txt": "7",
+	}, testGenerate(t, req))
+}
+
+func TestGenerateFrontMatter(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"message.tmpl": `---template
+path: "[[.File.Package]]/[[.Name]].go"
+expand: message
+filter: [notnested]
+format: gofmt
+delims: ["[[", "]]"]
+---
+package [[.File.Package]]
+type   [[.Name]]   struct{}
+`,
+		"partial.associated.tmpl": "---template\ndelims: [\"<<\", \">>\"]\n---\n<<.Name>>",
+		"index.txt.tmpl":          "---template\nskip_empty: true\n---\n",
+	})
+
+	testOutputs(t, map[string]string{
+		"testv2/Message.go":      "package testv2\n\ntype Message struct{}\n",
+		"testv2/OtherMessage.go": "package testv2\n\ntype OtherMessage struct{}\n",
+		"testv3/Message.go":      "package testv3\n\ntype Message struct{}\n",
+		"testv3/OtherMessage.go": "package testv3\n\ntype OtherMessage struct{}\n",
+	}, testGenerate(t, req))
+}
+
+func TestGenerateDocumentMarkers(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"k8s.yaml.tmpl": `---
+kind: ConfigMap
+data:
+  package: {{ (index .Files.ToGenerate 0).Package }}
+---
+kind: Service
+`,
+		"post.md.tmpl": `---
+title: {{ (index .Files.ToGenerate 0).Package }}
+layout: post
+---
+Body  
+`,
+	})
+
+	testOutputs(t, map[string]string{
+		"k8s.yaml": "kind: ConfigMap\ndata:\n  package: testv2\n---\nkind: Service\n",
+		"post.md":  "---\ntitle: testv2\nlayout: post\n---\nBody  \n",
+	}, testGenerate(t, req))
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	fm, body, err := parseFrontMatter([]byte("---template\nexpand: file\n---\n{{.Name}}"))
+	if err != nil {
+		t.Fatalf("parsing front matter: %s", err)
+	}
+	if fm.Expand != "file" {
+		t.Errorf("expected expand file, got %q", fm.Expand)
+	}
+	if body != "{{/*\n\n\n*/}}{{.Name}}" {
+		t.Errorf("expected front matter replaced by a comment, got %q", body)
+	}
+
+	for _, b := range []string{
+		"---template\nexpand: file\n",
+		"---template\nexpand: field\n---\n",
+		"---template\nunknown: true\n---\n",
+	} {
+		if _, _, err := parseFrontMatter([]byte(b)); err == nil {
+			t.Errorf("expected error parsing %q", b)
+		}
+	}
+}
+
+func TestGenerateCapture(t *testing.T) {
+	req := testRequest(t, nil)
+	dir := req.GetParameter()
+	filename := filepath.Join(dir, "capture", "request.pb")
+	parameter := "capture=" + filename + "," + dir
+	req.Parameter = &parameter
+
+	testGenerate(t, req)
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("reading captured request: %s", err)
+	}
+	actual := &plugin.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(b, actual); err != nil {
+		t.Fatalf("unmarshaling captured request: %s", err)
+	}
+	if actual.GetParameter() != dir {
+		t.Errorf("expected captured parameter %q, got %q", dir, actual.GetParameter())
+	}
+	actual.Parameter = req.Parameter
+	if !proto.Equal(req, actual) {
+		t.Errorf("captured request doesn't match request")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "capture", "request.json")); err != nil {
+		t.Errorf("expected JSON capture: %s", err)
+	}
+}
+
+func TestGenerateOptions(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"funcs.txt.tmpl": `{{ shout .Params.name }}`,
+	})
+
+	res, err := Generate(req, Options{
+		Params: map[string]string{"name": "foo"},
+		Funcs: template.FuncMap{
+			"shout": func(s string) string { return Upper(s) + "!" },
+		},
+	})
+	if err != nil {
+		t.Fatalf("generating files: %s", err)
+	}
+	if len(res.File) != 1 || res.File[0].GetContent() != "FOO!" {
+		t.Errorf("expected FOO!, got %v", res.File)
+	}
+
+	// Functions from one call aren't visible to another
+	if _, err := Generate(req, Options{}); err == nil {
+		t.Errorf("expected error for undefined function")
+	}
+}
+
+func TestGenerateFS(t *testing.T) {
+	req := testRequest(t, nil)
+	fsys := fstest.MapFS{
+		"templates/index.txt.tmpl": {Data: []byte("{{len .Files}}")},
+		"templates/static.txt":     {Data: []byte("static")},
+	}
+
+	res, err := Generate(req, Options{FS: fsys, Templates: "templates"})
+	if err != nil {
+		t.Fatalf("generating files: %s", err)
+	}
+	testOutputs(t, map[string]string{
+		"index.txt":  "4",
+		"static.txt": "static",
+	}, responseOutputs(res))
+}
+
+func TestGenerateBundles(t *testing.T) {
+	templates := map[string]string{
+		"index.txt.tmpl":          "{{len .Files}}",
+		"nested/static.txt":       "static",
+		"partial.associated.tmpl": "",
+	}
+	expected := map[string]string{
+		"index.txt":         "4",
+		"nested/static.txt": "static",
+	}
+	dir := testRequest(t, nil).GetParameter()
+
+	zipBuf := &bytes.Buffer{}
+	zw := zip.NewWriter(zipBuf)
+	for name, content := range templates {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("creating zip entry: %s", err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+
+	tarBuf := &bytes.Buffer{}
+	gz := gzip.NewWriter(tarBuf)
+	tw := tar.NewWriter(gz)
+	for name, content := range templates {
+		err := tw.WriteHeader(&tar.Header{Name: "./" + name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		if err != nil {
+			t.Fatalf("writing tar header: %s", err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+
+	for name, b := range map[string][]byte{"bundle.zip": zipBuf.Bytes(), "bundle.tar.gz": tarBuf.Bytes()} {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, b, 0644); err != nil {
+			t.Fatalf("writing bundle: %s", err)
+		}
+
+		req := testRequest(t, nil)
+		req.Parameter = &filename
+		testOutputs(t, expected, testGenerate(t, req))
+	}
+}
+
+func TestGenerateSingleFile(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"index.txt.tmpl": "{{len .Files}}",
+		"other.txt.tmpl": "other",
+	})
+	filename := filepath.Join(req.GetParameter(), "index.txt.tmpl")
+	req.Parameter = &filename
+
+	testOutputs(t, map[string]string{
+		"index.txt": "4",
+	}, testGenerate(t, req))
+}
+
+func TestGenerateLayers(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"shared/index.txt.tmpl":          `{{ template "partial" }}`,
+		"shared/partial.associated.tmpl": "shared",
+		"shared/static.txt":              "shared",
+		"shared/nested/shared.txt":       "shared",
+		"c++/partial.associated.tmpl":    "team",
+		"c++/nested/team.txt":            "team",
+	})
+	dir := req.GetParameter()
+	parameter := "template=" + filepath.Join(dir, "shared") + ",template=" + filepath.Join(dir, "c++")
+	req.Parameter = &parameter
+
+	testOutputs(t, map[string]string{
+		"index.txt":         "team",
+		"static.txt":        "shared",
+		"nested/shared.txt": "shared",
+		"nested/team.txt":   "team",
+	}, testGenerate(t, req))
+
+	fsys := fstest.MapFS{
+		"shared/index.txt.tmpl":          {Data: []byte(`{{ template "partial" }}`)},
+		"shared/partial.associated.tmpl": {Data: []byte("shared")},
+		"team/partial.associated.tmpl":   {Data: []byte("team")},
+	}
+	res, err := Generate(req, Options{FS: fsys, Templates: "shared", Layers: []string{"team"}})
+	if err != nil {
+		t.Fatalf("generating files: %s", err)
+	}
+	testOutputs(t, map[string]string{
+		"index.txt": "team",
+	}, responseOutputs(res))
+}
+
+func TestGenerateGoImports(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"out.go.tmpl": `package {{ gopackage "example.com/out" }}
+
+{{ goimports }}
+
+{{ range .Files.ToGenerate }}var _ {{ goimport . }}Message
+{{ end }}`,
+	})
+
+	testOutputs(t, map[string]string{
+		"out.go": "package out\n\nimport (\n\t\"testv2\"\n\t\"testv3\"\n)\n\nvar _ testv2.Message\nvar _ testv3.Message\n",
+	}, testGenerate(t, req))
+}
+
+func TestGenerateImports(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"protoc-gen-template/web/index.ts.tmpl": `{{ imports "ts" }}
+{{ range .Files.ToGenerate }}let _: {{ import "ts" . }}Message;
+{{ end }}`,
+		"protoc-gen-template/data/testdata/testv2_pb2.py.tmpl": `{{ imports "py" }}
+{{ range .Files.ToGenerate }}_ = {{ import "py" . }}Message
+{{ end }}`,
+	})
+
+	testOutputs(t, map[string]string{
+		"protoc-gen-template/web/index.ts": "import * as testv2_pb from \"../data/testdata/testv2_pb\";\n" +
+			"import * as testv3_pb from \"../data/testdata/testv3_pb\";\n\n" +
+			"let _: testv2_pb.Message;\n" +
+			"let _: testv3_pb.Message;\n",
+		"protoc-gen-template/data/testdata/testv2_pb2.py": "from protoc_gen_template.data.testdata import testv3_pb2\n\n" +
+			"_ = Message\n" +
+			"_ = testv3_pb2.Message\n",
+	}, testGenerate(t, req))
+}
+
+func TestGenerateEmittedImports(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"module.associated.tmpl": `{{ imports "ts" }}
+let _: {{ import "ts" . }}Message;
+`,
+		"package.associated.tmpl": `package {{ gopackage "example.com/out" }}
+
+{{ goimports }}
+
+var _ {{ goimport .GoPackageImport }}Message
+`,
+		"a/index.ts.tmpl": `{{ range .Files.ToGenerate }}{{ emit (printf "deep/x/y/%s.ts" .Package) (exec "module" .) }}{{ end }}{{ imports "ts" }}
+let _: {{ import "ts" (index .Files.ToGenerate 0) }}Message;
+`,
+		"out.go.tmpl": `{{ range .Files.ToGenerate }}{{ emit (printf "%s/out.go" .Package) (exec "package" .) }}{{ end }}package out
+
+{{ goimports }}
+`,
+	})
+	parameter := "format=none," + req.GetParameter()
+	req.Parameter = &parameter
+
+	testOutputs(t, map[string]string{
+		"a/index.ts": "import * as testv2_pb from \"../protoc-gen-template/data/testdata/testv2_pb\";\n\n" +
+			"let _: testv2_pb.Message;\n",
+		"deep/x/y/testv2.ts": "import * as testv2_pb from \"../../../protoc-gen-template/data/testdata/testv2_pb\";\n\n" +
+			"let _: testv2_pb.Message;\n",
+		"deep/x/y/testv3.ts": "import * as testv3_pb from \"../../../protoc-gen-template/data/testdata/testv3_pb\";\n\n" +
+			"let _: testv3_pb.Message;\n",
+		"out.go":        "package out\n\n\n",
+		"testv2/out.go": "package out\n\nimport (\n\t\"testv2\"\n)\n\n\nvar _ testv2.Message\n",
+		"testv3/out.go": "package out\n\nimport (\n\t\"testv3\"\n)\n\n\nvar _ testv3.Message\n",
+	}, testGenerate(t, req))
+}
+
+// testField returns a field as protoc would describe it.  The type name is
+// only set if it isn't empty, as for scalar fields.
+func testField(name string, number int32, label descriptor.FieldDescriptorProto_Label, typ descriptor.FieldDescriptorProto_Type, typeName string) *descriptor.FieldDescriptorProto {
+	f := &descriptor.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    label.Enum(),
+		Type:     typ.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+// testExtension returns an optional field extending the named message
+func testExtension(name string, number int32, typ descriptor.FieldDescriptorProto_Type, typeName, extendee string) *descriptor.FieldDescriptorProto {
+	f := testField(name, number, descriptor.FieldDescriptorProto_LABEL_OPTIONAL, typ, typeName)
+	f.Extendee = proto.String(extendee)
+	return f
+}
+
+// inOneof adds a field to the oneof with the given index
+func inOneof(f *descriptor.FieldDescriptorProto, index int32) *descriptor.FieldDescriptorProto {
+	f.OneofIndex = proto.Int32(index)
+	return f
+}
+
+// withOptions sets a field's options
+func withOptions(f *descriptor.FieldDescriptorProto, options *descriptor.FieldOptions) *descriptor.FieldDescriptorProto {
+	f.Options = options
+	return f
+}
+
+// testProto3File returns a proto3 file containing a message with map, oneof
+// and optional fields, as protoc would describe it
+func testProto3File() *descriptor.FileDescriptorProto {
+	optional := inOneof(testField("count", 4, descriptor.FieldDescriptorProto_LABEL_OPTIONAL, descriptor.FieldDescriptorProto_TYPE_INT32, ""), 1)
+	optional.Proto3Optional = proto.Bool(true)
+
+	return &descriptor.FileDescriptorProto{
+		Name:           proto.String("maps/maps.proto"),
+		Package:        proto.String("maps"),
+		Syntax:         proto.String("proto3"),
+		SourceCodeInfo: &descriptor.SourceCodeInfo{},
+		MessageType: []*descriptor.DescriptorProto{{
+			Name: proto.String("Inventory"),
+			Field: []*descriptor.FieldDescriptorProto{
+				testField("items", 1, descriptor.FieldDescriptorProto_LABEL_REPEATED, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".maps.Inventory.ItemsEntry"),
+				testField("tags", 2, descriptor.FieldDescriptorProto_LABEL_REPEATED, descriptor.FieldDescriptorProto_TYPE_STRING, ""),
+				inOneof(testField("name", 3, descriptor.FieldDescriptorProto_LABEL_OPTIONAL, descriptor.FieldDescriptorProto_TYPE_STRING, ""), 0),
+				optional,
+				testField("total", 5, descriptor.FieldDescriptorProto_LABEL_OPTIONAL, descriptor.FieldDescriptorProto_TYPE_INT32, ""),
+			},
+			OneofDecl: []*descriptor.OneofDescriptorProto{
+				{Name: proto.String("label")},
+				{Name: proto.String("_count")},
+			},
+			NestedType: []*descriptor.DescriptorProto{
+				{
+					Name:    proto.String("ItemsEntry"),
+					Options: &descriptor.MessageOptions{MapEntry: proto.Bool(true)},
+					Field: []*descriptor.FieldDescriptorProto{
+						testField("key", 1, descriptor.FieldDescriptorProto_LABEL_OPTIONAL, descriptor.FieldDescriptorProto_TYPE_STRING, ""),
+						testField("value", 2, descriptor.FieldDescriptorProto_LABEL_OPTIONAL, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".maps.Inventory.Item"),
+					},
+				},
+				{Name: proto.String("Item")},
+			},
+		}},
+	}
+}
+
+func TestGenerateMapFields(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"maps.txt.tmpl": `{{ range .Messages.ToGenerate }}{{ .Name }}
+{{ range .Fields }}{{ .Name }} {{ .IsMap }} {{ .TypeNameString }}{{ with .MapKey }} {{ .Name }}{{ end }}{{ with .MapValue }} {{ .TypeMessage.Name }}{{ end }}
+{{ end }}{{ end }}`,
+	})
+	req.ProtoFile = append(req.ProtoFile, testProto3File())
+	req.FileToGenerate = []string{"maps/maps.proto"}
+
+	testOutputs(t, map[string]string{
+		"maps.txt": "Inventory\n" +
+			"items true map<string,maps.Inventory.Item> key Item\n" +
+			"tags false []string\n" +
+			"name false string\n" +
+			"count false int32\n" +
+			"total false int32\n" +
+			"Item\n",
+	}, testGenerate(t, req))
+}
+
+func TestGenerateProto3Optional(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"fields.txt.tmpl": `{{ range .Messages.ToGenerate }}{{ range .Fields }}{{ .Name }} {{ .HasPresence }} {{ .IsProto3Optional }} {{ .IsOneof }}
+{{ end }}{{ range .Oneofs }}oneof {{ .Name }}
+{{ end }}{{ range .SyntheticOneofs }}synthetic {{ .Name }} {{ .IsSynthetic }}
+{{ end }}{{ end }}{{ len .Oneofs }} {{ len .SyntheticOneofs }}`,
+	})
+	req.ProtoFile = append(req.ProtoFile, testProto3File())
+	req.FileToGenerate = []string{"maps/maps.proto"}
+
+	res, err := Generate(req, Options{})
+	if err != nil {
+		t.Fatalf("generating files: %s", err)
+	}
+	if res.GetSupportedFeatures()&uint64(plugin.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL) == 0 {
+		t.Errorf("expected proto3 optional to be supported")
+	}
+
+	testOutputs(t, map[string]string{
+		"fields.txt": "items false false false\n" +
+			"tags false false false\n" +
+			"name true false true\n" +
+			"count true true false\n" +
+			"total false false false\n" +
+			"oneof label\n" +
+			"synthetic _count true\n" +
+			"3 1",
+	}, responseOutputs(res))
+}
+
+// testEditionsFile returns an edition 2023 file overriding features at each
+// level, as protoc would describe it
+func testEditionsFile() *descriptor.FileDescriptorProto {
+	return &descriptor.FileDescriptorProto{
+		Name:           proto.String("editions/editions.proto"),
+		Package:        proto.String("editions"),
+		Syntax:         proto.String("editions"),
+		Edition:        descriptor.Edition_EDITION_2023.Enum(),
+		SourceCodeInfo: &descriptor.SourceCodeInfo{},
+		Options: &descriptor.FileOptions{Features: &descriptor.FeatureSet{
+			FieldPresence: descriptor.FeatureSet_IMPLICIT.Enum(),
+		}},
+		MessageType: []*descriptor.DescriptorProto{{
+			Name: proto.String("Config"),
+			Options: &descriptor.MessageOptions{Features: &descriptor.FeatureSet{
+				EnumType:   descriptor.FeatureSet_CLOSED.Enum(),
+				JsonFormat: descriptor.FeatureSet_LEGACY_BEST_EFFORT.Enum(),
+			}},
+			Field: []*descriptor.FieldDescriptorProto{
+				testField("count", 1, descriptor.FieldDescriptorProto_LABEL_OPTIONAL, descriptor.FieldDescriptorProto_TYPE_INT32, ""),
+				withOptions(testField("limit", 2, descriptor.FieldDescriptorProto_LABEL_OPTIONAL, descriptor.FieldDescriptorProto_TYPE_INT32, ""), &descriptor.FieldOptions{
+					Features: &descriptor.FeatureSet{FieldPresence: descriptor.FeatureSet_EXPLICIT.Enum()},
+				}),
+				testField("ids", 3, descriptor.FieldDescriptorProto_LABEL_REPEATED, descriptor.FieldDescriptorProto_TYPE_INT32, ""),
+				withOptions(testField("raw", 4, descriptor.FieldDescriptorProto_LABEL_OPTIONAL, descriptor.FieldDescriptorProto_TYPE_STRING, ""), &descriptor.FieldOptions{
+					Features: &descriptor.FeatureSet{Utf8Validation: descriptor.FeatureSet_NONE.Enum()},
+				}),
+			},
+			Extension: []*descriptor.FieldDescriptorProto{
+				testExtension("config_key", 50100, descriptor.FieldDescriptorProto_TYPE_STRING, "", ".google.protobuf.FieldOptions"),
+			},
+			EnumType: []*descriptor.EnumDescriptorProto{{
+				Name:  proto.String("Mode"),
+				Value: []*descriptor.EnumValueDescriptorProto{{Name: proto.String("MODE_UNSPECIFIED"), Number: proto.Int32(0)}},
+			}},
+		}},
+		EnumType: []*descriptor.EnumDescriptorProto{{
+			Name:  proto.String("Level"),
+			Value: []*descriptor.EnumValueDescriptorProto{{Name: proto.String("LEVEL_UNSPECIFIED"), Number: proto.Int32(0)}},
+		}},
+	}
+}
+
+func TestGenerateEditions(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"features.txt.tmpl": `{{ range .Files }}{{ .Edition }}
+{{ end }}{{ range .Files.ToGenerate }}{{ range .Messages }}{{ .Name }} {{ .Features.JSONFormat }}
+{{ range .Fields }}{{ .Name }} {{ .HasPresence }} {{ .IsPacked }} {{ .ValidatesUTF8 }} {{ .Features.FieldPresence }}
+{{ end }}{{ range .Enums }}{{ .Name }} {{ .IsClosed }}
+{{ end }}{{ end }}{{ range .Enums }}{{ .Name }} {{ .IsClosed }}
+{{ end }}{{ end }}`,
+	})
+	req.ProtoFile = append(req.ProtoFile, testEditionsFile())
+	req.FileToGenerate = []string{"editions/editions.proto"}
+
+	res, err := Generate(req, Options{})
+	if err != nil {
+		t.Fatalf("generating files: %s", err)
+	}
+	if res.GetSupportedFeatures()&uint64(plugin.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS) == 0 {
+		t.Errorf("expected editions to be supported")
+	}
+	if res.GetMaximumEdition() != int32(descriptor.Edition_EDITION_2023) {
+		t.Errorf("expected maximum edition 2023, got %d", res.GetMaximumEdition())
+	}
+
+	testOutputs(t, map[string]string{
+		"features.txt": "proto2\nproto3\nproto2\nproto3\n2023\n" +
+			"Config LEGACY_BEST_EFFORT\n" +
+			"count false false false IMPLICIT\n" +
+			"limit true false false EXPLICIT\n" +
+			"ids false true false IMPLICIT\n" +
+			"raw false false false IMPLICIT\n" +
+			"Mode true\n" +
+			"Level false\n",
+	}, responseOutputs(res))
+}
+
+func TestGenerateExtensions(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"extensions.txt.tmpl": `{{ range .Extensions }}{{ .FullName }} {{ .Number }} {{ .Extendee.Name }} {{ .TypeNameString }} {{ .IsNested }}
+{{ end }}{{ range .Files.ToGenerate }}{{ len .Extensions }}{{ range .Messages }} {{ .Name }} {{ len .Extensions }}{{ end }}
+{{ end }}{{ range .Messages }}{{ if eq .Name "FieldOptions" }}{{ .ExtensionRanges }}{{ range .ExtendedBy }} {{ .Name }}{{ with .Scope }} {{ .Name }}{{ end }}{{ end }}{{ end }}{{ end }}`,
+	})
+	req.ProtoFile = append(req.ProtoFile, testEditionsFile())
+	req.FileToGenerate = []string{"editions/editions.proto"}
+
+	testOutputs(t, map[string]string{
+		"extensions.txt": "template.file_meta 50001 FileOptions template.FileMetadata false\n" +
+			"template.message_meta 50001 MessageOptions template.MessageMetadata false\n" +
+			"template.field_meta 50001 FieldOptions template.FieldMetadata false\n" +
+			"template.enum_meta 50001 EnumOptions template.EnumMetadata false\n" +
+			"template.enum_value_meta 50001 EnumValueOptions template.EnumValueMetadata false\n" +
+			"template.service_meta 50001 ServiceOptions template.ServiceMetadata false\n" +
+			"template.method_meta 50001 MethodOptions template.MethodMetadata false\n" +
+			"editions.Config.config_key 50100 FieldOptions string true\n" +
+			"0 Config 1\n" +
+			"[{1000 536870912}] field_meta config_key Config",
+	}, testGenerate(t, req))
+}
+
+// testOptionsFile returns a file defining custom field options and a message
+// using them.  The options are unknown to this binary, so they're stored as
+// unknown fields.
+func testOptionsFile() *descriptor.FileDescriptorProto {
+	var unknown []byte
+	unknown = protowire.AppendTag(unknown, 50200, protowire.VarintType)
+	unknown = protowire.AppendVarint(unknown, 1)
+	unknown = protowire.AppendTag(unknown, 50201, protowire.VarintType)
+	unknown = protowire.AppendVarint(unknown, 2)
+	options := &descriptor.FieldOptions{}
+	options.ProtoReflect().SetUnknown(unknown)
+
+	return &descriptor.FileDescriptorProto{
+		Name:           proto.String("acme/options.proto"),
+		Package:        proto.String("acme"),
+		Syntax:         proto.String("proto3"),
+		Dependency:     []string{"google/protobuf/descriptor.proto"},
+		SourceCodeInfo: &descriptor.SourceCodeInfo{},
+		EnumType: []*descriptor.EnumDescriptorProto{{
+			Name: proto.String("Level"),
+			Value: []*descriptor.EnumValueDescriptorProto{
+				{Name: proto.String("LEVEL_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("LEVEL_LOW"), Number: proto.Int32(1)},
+				{Name: proto.String("LEVEL_HIGH"), Number: proto.Int32(2)},
+			},
+		}},
+		Extension: []*descriptor.FieldDescriptorProto{
+			testExtension("sensitive", 50200, descriptor.FieldDescriptorProto_TYPE_BOOL, "", ".google.protobuf.FieldOptions"),
+			testExtension("level", 50201, descriptor.FieldDescriptorProto_TYPE_ENUM, ".acme.Level", ".google.protobuf.FieldOptions"),
+		},
+		MessageType: []*descriptor.DescriptorProto{{
+			Name: proto.String("User"),
+			Field: []*descriptor.FieldDescriptorProto{
+				withOptions(testField("password", 1, descriptor.FieldDescriptorProto_LABEL_OPTIONAL, descriptor.FieldDescriptorProto_TYPE_STRING, ""), options),
+				testField("name", 2, descriptor.FieldDescriptorProto_LABEL_OPTIONAL, descriptor.FieldDescriptorProto_TYPE_STRING, ""),
+			},
+		}},
+	}
+}
+
+func TestGenerateOption(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"options.txt.tmpl": `{{ range .Messages.ToGenerate }}{{ range .Fields }}{{ .Name }} {{ option . "acme.sensitive" }} {{ option . "acme.level" }}
+{{ end }}{{ end }}{{ range .Files }}{{ with option . "template.file_meta" }}{{ .tags }} {{ .visibility }} {{ .extra.k }}
+{{ end }}{{ end }}`,
+	})
+	req.ProtoFile = append(req.ProtoFile, testOptionsFile())
+	req.FileToGenerate = []string{"acme/options.proto"}
+
+	testOutputs(t, map[string]string{
+		"options.txt": "password true LEVEL_HIGH\n" +
+			"name <no value> <no value>\n" +
+			"[tag1 tag2] PRIVATE v\n" +
+			"[tag1 tag2] PRIVATE v\n",
+	}, testGenerate(t, req))
+}
+
+func TestGenerateUnresolved(t *testing.T) {
+	req := testRequest(t, map[string]string{"out.txt.tmpl": "{{ len .Files }}"})
+	file := testProto3File()
+	file.MessageType[0].Field[0].TypeName = proto.String(".maps.Missing")
+	req.ProtoFile = append(req.ProtoFile, file)
+
+	_, err := Generate(req, Options{})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	expected := "maps/maps.proto: field maps.Inventory.items refers to unresolved type .maps.Missing"
+	if !strings.HasSuffix(err.Error(), expected) {
+		t.Errorf("expected error ending with %q, got %q", expected, err)
+	}
+}
+
+func TestGenerateOrdering(t *testing.T) {
+	outputs := responseOutputs(mustGenerate(t, testRequest(t, map[string]string{
+		"order.txt.tmpl": `{{ range .Enums.ToGenerate }}{{ .FullName }} {{ end }}`,
+	})))
+	if !strings.HasPrefix(outputs["order.txt"], "testv2.Message.EmbeddedEnum testv2.Message.OtherEmbeddedEnum testv2.Enum testv2.OtherEnum testv3.Message.EmbeddedEnum") {
+		t.Errorf("expected enums in declaration order, got %q", outputs["order.txt"])
+	}
+}
+
+func TestGenerateSorting(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"sorted.txt.tmpl": `{{ range sortbyname .Messages.ToGenerate.NotNested }}{{ .FullName }} {{ end }}
+{{ range sortbyfullname .Messages.ToGenerate }}{{ .FullName }} {{ end }}
+{{ range .Files.ToGenerate }}{{ range .Messages }}{{ if eq .Name "Message" }}{{ range sortbynumber (sortbyname .Fields) }}{{ .Number }} {{ end }}{{ end }}{{ end }}{{ end }}`,
+	})
+
+	testOutputs(t, map[string]string{
+		"sorted.txt": "testv2.Message testv3.Message testv2.OtherMessage testv3.OtherMessage\n" +
+			"testv2.Message testv2.Message.EmbeddedMessage testv2.Message.OtherEmbeddedMessage testv2.OtherMessage " +
+			"testv3.Message testv3.Message.EmbeddedMessage testv3.Message.OtherEmbeddedMessage testv3.OtherMessage\n" +
+			"1 2 3 4 5 6 7 1 2 3 4 5 6 7",
+	}, testGenerate(t, req))
+
+	for _, tmpl := range []string{
+		`{{ sortbynumber .Messages }}`,
+		`{{ sortbyfullname .Files }}`,
+		`{{ sortbyname .Params }}`,
+	} {
+		req := testRequest(t, map[string]string{"sorted.txt.tmpl": tmpl})
+		if _, err := Generate(req, Options{}); err == nil {
+			t.Errorf("%s: expected error", tmpl)
+		}
+	}
+}
+
+func TestGenerateLocations(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"locations.txt.tmpl": `{{ range .Files.ToGenerate }}{{ range .Messages.NotNested }}{{ .Location }} {{ .Location.StartColumn }}
+{{ end }}{{ range .Services }}{{ range .Methods }}{{ .Location }}
+{{ end }}{{ end }}{{ end }}`,
+	})
+
+	testOutputs(t, map[string]string{
+		"locations.txt": "protoc-gen-template/data/testdata/testv2.proto:61 1\n" +
+			"protoc-gen-template/data/testdata/testv2.proto:116 1\n" +
+			"protoc-gen-template/data/testdata/testv2.proto:27\n" +
+			"protoc-gen-template/data/testdata/testv3.proto:64 1\n" +
+			"protoc-gen-template/data/testdata/testv3.proto:119 1\n" +
+			"protoc-gen-template/data/testdata/testv3.proto:30\n",
+	}, testGenerate(t, req))
+}
+
+func TestGenerateComments(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"comments.txt.tmpl": `{{ range .Messages.ToGenerate }}{{ .Name }}: {{ .Comments.Summary }}
+{{ .Comments.Body }}
+{{ .Comments.Directive "since" }} {{ .Comments.HasDirective "internal" }} {{ .Comments.HasDirective "todo" }}
+{{ .Comments.Directive "example" }}
+{{ range $tag, $value := .Comments.Directives }}{{ $tag }} {{ end }}
+{{ range .Fields }}{{ with .Comments.Text }}{{ $.Params.prefix }}{{ . }}
+{{ end }}{{ end }}{{ end }}`,
+	})
+	file := testProto3File()
+	file.SourceCodeInfo.Location = []*descriptor.SourceCodeInfo_Location{
+		{
+			Path:            []int32{4, 0},
+			LeadingComments: proto.String("*\n * An inventory of items,\n * keyed by SKU.\n *\n *   Indented details.\n *\n * @since v2\n * @internal\n * @example {\n *   \"items\": {}\n * }\n * @todo stays in the text\n "),
+		},
+		{
+			Path:            []int32{4, 0, 2, 1},
+			LeadingComments: proto.String(" Tags applied to the inventory\n @deprecated use labels\n"),
+		},
+	}
+	req.ProtoFile = append(req.ProtoFile, file)
+	req.FileToGenerate = []string{"maps/maps.proto"}
+	req.Parameter = proto.String(req.GetParameter() + ",prefix=>")
+
+	testOutputs(t, map[string]string{
+		"comments.txt": "Inventory: An inventory of items, keyed by SKU.\n" +
+			"  Indented details.\n\n@todo stays in the text\n" +
+			"v2 true false\n" +
+			"{\n  \"items\": {}\n}\n" +
+			"example internal since\n" +
+			">Tags applied to the inventory\n" +
+			"Item:\n\n" +
+			" false false\n",
+	}, testGenerate(t, req))
+
+	// Only the configured tags are parsed
+	req.Parameter = proto.String(req.GetParameter() + ",comment_tag=todo")
+	res := responseOutputs(mustGenerate(t, req))
+	if !strings.Contains(res["comments.txt"], "\n false true\n\ntodo\n") {
+		t.Errorf("expected configured directives, got %q", res["comments.txt"])
+	}
+}
+
+func TestGenerateLookups(t *testing.T) {
+	req := testRequest(t, map[string]string{
+		"lookups.txt.tmpl": `{{ (.Message "testv2.Message.EmbeddedMessage").FullName }}
+{{ (.Message ".testv3.OtherMessage").FullName }}
+{{ (.Message "EmbeddedMessage" "testv2.Message").FullName }}
+{{ (.Message "Message" "testv3.Message.EmbeddedMessage").FullName }}
+{{ (.Enum "OtherEnum" ".testv2.Message").FullName }}
+{{ (.Service "Service" "testv3").FullName }}
+{{ (.Method "testv2.Service.Method").FullName }}
+{{ (.File "protoc-gen-template/data/testdata/testv3.proto").Package }}
+{{ if not (.Message "EmbeddedMessage") }}missing{{ end }} {{ if not (.Method "testv2.Service.Missing") }}missing{{ end }}`,
+	})
+
+	testOutputs(t, map[string]string{
+		"lookups.txt": "testv2.Message.EmbeddedMessage\n" +
+			"testv3.OtherMessage\n" +
+			"testv2.Message.EmbeddedMessage\n" +
+			"testv3.Message\n" +
+			"testv2.OtherEnum\n" +
+			"testv3.Service\n" +
+			"testv2.Service.Method\n" +
+			"testv3\n" +
+			"missing missing",
+	}, testGenerate(t, req))
+}
+
+func mustGenerate(t *testing.T, req *plugin.CodeGeneratorRequest) *plugin.CodeGeneratorResponse {
+	res, err := Generate(req, Options{})
+	if err != nil {
+		t.Fatalf("generating files: %s", err)
+	}
+	return res
+}
@@ -0,0 +1,180 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/kerinin/protoc-gen-template/data"
+	"github.com/pkg/errors"
+)
+
+// goImportsPlaceholder marks where `goimports` was called, and is replaced by
+// the import block once the template has been executed
+const goImportsPlaceholder = "/*protoc-gen-template:goimports*/"
+
+var (
+	// goPackageMarker records the package of a generated Go file.  It's
+	// removed once the output is complete.
+	goPackageMarker = regexp.MustCompile(`/\*protoc-gen-template:gopackage:([^*]*)\*/`)
+
+	// goImportMarker records an import of a package, and is replaced by the
+	// package's qualifier once the output is complete
+	goImportMarker = regexp.MustCompile(`/\*protoc-gen-template:goimport:([^ *]*) ([^*]*)\*/`)
+)
+
+// GoPackage records the import path of the generated file's own package, so
+// references to it aren't qualified, and returns its package name.  It
+// accepts a File or an import path.
+//
+// Example:
+//
+//	package {{ gopackage .File }}
+func GoPackage(v interface{}) (string, error) {
+	importPath, name, err := goPackage(v)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("/*protoc-gen-template:gopackage:%s*/%s", importPath, name), nil
+}
+
+// GoImport records an import of the package of the given File or import path,
+// returning the qualifier to use for its identifiers.  The qualifier is empty
+// for the generated file's own package.  Imports are collected separately for
+// each generated file, including files written with `emit`, so qualifiers are
+// only resolved once the file is complete.
+//
+// Example:
+//
+//	{{ goimport .TypeMessage.File }}{{ .TypeMessage.Name }}  -> "otherpb.Message"
+func GoImport(v interface{}) (string, error) {
+	importPath, name, err := goPackage(v)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("/*protoc-gen-template:goimport:%s %s*/", importPath, name), nil
+}
+
+// GoImports returns a placeholder which is replaced by an import declaration
+// listing every package recorded with `goimport` in the generated file,
+// including those recorded later in the template.
+//
+// Example:
+//
+//	package {{ gopackage .File }}
+//
+//	{{ goimports }}
+func GoImports() string {
+	return goImportsPlaceholder
+}
+
+// goImports collects the packages imported by a generated Go file, assigning
+// each a unique alias
+type goImports struct {
+	self    string            // Import path of the file's own package
+	aliases map[string]string // Import path -> alias
+	paths   map[string]string // Alias -> import path
+}
+
+// applyGoImports resolves the package and import markers in a generated file,
+// and replaces the import placeholder with the file's import declaration
+func applyGoImports(content string) string {
+	g := &goImports{
+		aliases: map[string]string{},
+		paths:   map[string]string{},
+	}
+	if m := goPackageMarker.FindStringSubmatch(content); m != nil {
+		g.self = m[1]
+	}
+	content = goPackageMarker.ReplaceAllString(content, "")
+	content = goImportMarker.ReplaceAllStringFunc(content, func(marker string) string {
+		m := goImportMarker.FindStringSubmatch(marker)
+		return g.add(m[1], m[2])
+	})
+	return g.apply(content)
+}
+
+// add records an import of a package, returning the qualifier for its
+// identifiers
+func (g *goImports) add(importPath, name string) string {
+	if importPath == g.self {
+		return ""
+	}
+	if alias, found := g.aliases[importPath]; found {
+		return alias + "."
+	}
+
+	// Aliases are suffixed with a number when another package or a keyword
+	// uses the same name
+	base := goIdentifier(name)
+	alias := base
+	for i := 2; g.paths[alias] != "" || token.IsKeyword(alias); i++ {
+		alias = fmt.Sprintf("%s%d", base, i)
+	}
+
+	g.aliases[importPath] = alias
+	g.paths[alias] = importPath
+	return alias + "."
+}
+
+// apply replaces the import placeholder in content with the import declaration
+func (g *goImports) apply(content string) string {
+	if !strings.Contains(content, goImportsPlaceholder) {
+		return content
+	}
+
+	paths := make([]string, 0, len(g.aliases))
+	for importPath := range g.aliases {
+		paths = append(paths, importPath)
+	}
+	sort.Strings(paths)
+
+	buffer := &bytes.Buffer{}
+	if len(paths) > 0 {
+		buffer.WriteString("import (\n")
+		for _, importPath := range paths {
+			if alias := g.aliases[importPath]; alias != path.Base(importPath) {
+				fmt.Fprintf(buffer, "\t%s %q\n", alias, importPath)
+			} else {
+				fmt.Fprintf(buffer, "\t%q\n", importPath)
+			}
+		}
+		buffer.WriteString(")\n")
+	}
+
+	content = strings.Replace(content, goImportsPlaceholder, buffer.String(), 1)
+	return strings.Replace(content, goImportsPlaceholder, "", -1)
+}
+
+// goPackage returns the import path and package name of a File or import path
+func goPackage(v interface{}) (string, string, error) {
+	switch v := v.(type) {
+	case data.File:
+		return v.GoPackageImport(), v.GoPackageName(), nil
+	case *data.File:
+		return v.GoPackageImport(), v.GoPackageName(), nil
+	case string:
+		return v, path.Base(v), nil
+	default:
+		return "", "", errors.Errorf("expected a File or import path, got %T", v)
+	}
+}
+
+// goIdentifier replaces characters which aren't valid in Go identifiers
+func goIdentifier(s string) string {
+	b := []rune(s)
+	for i, r := range b {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 {
+		return "_"
+	}
+	return string(b)
+}
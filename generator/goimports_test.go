@@ -0,0 +1,45 @@
+package generator
+
+import (
+	"testing"
+)
+
+func TestGoImports(t *testing.T) {
+	content, err := GoPackage("example.com/out")
+	if err != nil {
+		t.Fatalf("setting package: %s", err)
+	}
+	content += "\n" + GoImports()
+
+	for _, importPath := range []string{
+		"example.com/a/foo",
+		"example.com/b/foo",
+		"example.com/a/foo",
+		"example.com/type",
+		"example.com/foo-bar",
+		"example.com/out",
+	} {
+		qualifier, err := GoImport(importPath)
+		if err != nil {
+			t.Errorf("%s: importing: %s", importPath, err)
+		}
+		content += "\n" + qualifier + "X"
+	}
+	content += "\n" + GoImports()
+
+	expected := "out\n" +
+		"import (\n" +
+		"\t\"example.com/a/foo\"\n" +
+		"\tfoo2 \"example.com/b/foo\"\n" +
+		"\tfoo_bar \"example.com/foo-bar\"\n" +
+		"\ttype2 \"example.com/type\"\n" +
+		")\n\n" +
+		"foo.X\nfoo2.X\nfoo.X\ntype2.X\nfoo_bar.X\nX\n"
+	if actual := applyGoImports(content); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	if _, err := GoImport(1); err == nil {
+		t.Errorf("expected error importing an int")
+	}
+}
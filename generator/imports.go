@@ -0,0 +1,253 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kerinin/protoc-gen-template/data"
+	"github.com/pkg/errors"
+)
+
+// importStrategy describes how a language refers to the modules generated for
+// proto files
+type importStrategy struct {
+	suffixParam   string                                     // Parameter overriding the module suffix
+	defaultSuffix string                                     // Suffix appended to the proto file's name, ie "_pb"
+	module        func(filename string) string               // Module generated for a proto file, without suffix
+	self          func(outPath string) string                // Module of the output being generated
+	statement     func(outPath, module, alias string) string // Import statement for a module
+}
+
+// importStrategies maps language names to import strategies
+var importStrategies = map[string]importStrategy{
+	// ES modules, imported as namespaces using paths relative to the output
+	"ts": {
+		suffixParam:   "ts_module_suffix",
+		defaultSuffix: "_pb",
+		module:        TrimExt,
+		self:          TrimExt,
+		statement: func(outPath, module, alias string) string {
+			rel := relativeModule(path.Dir(outPath), module)
+			return fmt.Sprintf("import * as %s from %q;", alias, rel)
+		},
+	},
+
+	// Python modules, imported using dotted module names
+	"py": {
+		suffixParam:   "py_module_suffix",
+		defaultSuffix: "_pb2",
+		module:        pythonModule,
+		self:          pythonModule,
+		statement: func(_, module, alias string) string {
+			pkg, name := "", module
+			if i := strings.LastIndex(module, "."); i >= 0 {
+				pkg, name = module[:i], module[i+1:]
+			}
+
+			stmt := "import " + name
+			if pkg != "" {
+				stmt = "from " + pkg + " " + stmt
+			}
+			if alias != name {
+				stmt += " as " + alias
+			}
+			return stmt
+		},
+	},
+}
+
+// moduleImportMarker records an import of the module generated for a proto
+// file, and is replaced by the module's qualifier once the output is complete
+var moduleImportMarker = regexp.MustCompile(`<<protoc-gen-template:import:([a-z]+):([^>]*)>>`)
+
+// moduleImportsPlaceholder marks where `imports` was called for a language,
+// and is replaced by the import statements once the template has been
+// executed
+func moduleImportsPlaceholder(lang string) string {
+	return fmt.Sprintf("<<protoc-gen-template:imports:%s>>", lang)
+}
+
+// Import records an import of the module generated for the proto file
+// defining a File, Message or Enum, using the named language's strategy.  It
+// returns the qualifier for a File, or the qualified name of a Message or
+// Enum.  Values defined in the output's own module aren't qualified.  Imports
+// are collected separately for each generated file, including files written
+// with `emit`, so qualifiers are only resolved once the file is complete.
+//
+// Example:
+//
+//	{{ range .Fields }}{{ with .TypeMessage }}
+//	  {{ import "ts" . }}   -> "other_pb.Message.Nested"
+//	{{ end }}{{ end }}
+func Import(lang string, v interface{}) (string, error) {
+	if _, found := importStrategies[lang]; !found {
+		return "", errors.Errorf("unknown import language %q", lang)
+	}
+
+	var file, name string
+	switch v := v.(type) {
+	case data.File:
+		file = v.Name
+	case *data.File:
+		file = v.Name
+	case data.Message:
+		file, name = v.File().Name, localName(v.String(), v.File().Package)
+	case *data.Message:
+		file, name = v.File().Name, localName(v.String(), v.File().Package)
+	case data.Enum:
+		file, name = v.File().Name, localName(v.String(), v.File().Package)
+	case *data.Enum:
+		file, name = v.File().Name, localName(v.String(), v.File().Package)
+	default:
+		return "", errors.Errorf("expected a File, Message or Enum, got %T", v)
+	}
+
+	return fmt.Sprintf("<<protoc-gen-template:import:%s:%s>>%s", lang, file, name), nil
+}
+
+// Imports returns a placeholder which is replaced by the import statements
+// for every module recorded with `import` for the named language in the
+// generated file, including those recorded later in the template.
+//
+// Example:
+//
+//	{{ imports "ts" }}
+func Imports(lang string) (string, error) {
+	if _, found := importStrategies[lang]; !found {
+		return "", errors.Errorf("unknown import language %q", lang)
+	}
+	return moduleImportsPlaceholder(lang), nil
+}
+
+// moduleImports collects the modules imported by a generated file for a
+// single language, assigning each a unique alias
+type moduleImports struct {
+	strategy importStrategy
+	outPath  string
+	suffix   string
+	aliases  map[string]string // Module -> alias
+	modules  map[string]string // Alias -> module
+}
+
+// applyModuleImports resolves the import markers in a generated file, and
+// replaces each language's import placeholder with the file's import
+// statements.  Module suffixes are read from params.
+func applyModuleImports(outPath string, params map[string]string, content string) string {
+	imports := make(map[string]*moduleImports, len(importStrategies))
+	for lang, strategy := range importStrategies {
+		suffix, found := params[strategy.suffixParam]
+		if !found {
+			suffix = strategy.defaultSuffix
+		}
+		imports[lang] = &moduleImports{
+			strategy: strategy,
+			outPath:  outPath,
+			suffix:   suffix,
+			aliases:  map[string]string{},
+			modules:  map[string]string{},
+		}
+	}
+
+	content = moduleImportMarker.ReplaceAllStringFunc(content, func(marker string) string {
+		m := moduleImportMarker.FindStringSubmatch(marker)
+		return imports[m[1]].add(m[2])
+	})
+	for lang, imports := range imports {
+		content = imports.apply(lang, content)
+	}
+	return content
+}
+
+// add records an import of the module generated for the named proto file,
+// returning the qualifier for its identifiers
+func (m *moduleImports) add(filename string) string {
+	module := m.strategy.module(filename) + m.suffix
+	if module == m.strategy.self(m.outPath) {
+		return ""
+	}
+	if alias, found := m.aliases[module]; found {
+		return alias + "."
+	}
+
+	// Aliases are suffixed with a number when another module uses the same name
+	base := goIdentifier(path.Base(strings.Replace(module, ".", "/", -1)))
+	alias := base
+	for i := 2; m.modules[alias] != ""; i++ {
+		alias = fmt.Sprintf("%s%d", base, i)
+	}
+
+	m.aliases[module] = alias
+	m.modules[alias] = module
+	return alias + "."
+}
+
+// apply replaces the language's import placeholder in content with the
+// import statements
+func (m *moduleImports) apply(lang, content string) string {
+	placeholder := moduleImportsPlaceholder(lang)
+	if !strings.Contains(content, placeholder) {
+		return content
+	}
+
+	modules := make([]string, 0, len(m.aliases))
+	for module := range m.aliases {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	buffer := &bytes.Buffer{}
+	for _, module := range modules {
+		buffer.WriteString(m.strategy.statement(m.outPath, module, m.aliases[module]))
+		buffer.WriteString("\n")
+	}
+
+	content = strings.Replace(content, placeholder, buffer.String(), 1)
+	return strings.Replace(content, placeholder, "", -1)
+}
+
+// localName returns a type's name relative to its package, ie "Outer.Inner"
+func localName(id, pkg string) string {
+	if pkg == "" {
+		return strings.TrimPrefix(id, ".")
+	}
+	return strings.TrimPrefix(id, "."+pkg+".")
+}
+
+// relativeModule returns the ES module path of module relative to dir
+func relativeModule(dir, module string) string {
+	var (
+		from = strings.Split(path.Clean(dir), "/")
+		to   = strings.Split(path.Clean(module), "/")
+	)
+	if dir == "." || dir == "" {
+		from = nil
+	}
+
+	i := 0
+	for i < len(from) && i < len(to)-1 && from[i] == to[i] {
+		i++
+	}
+
+	parts := []string{}
+	for range from[i:] {
+		parts = append(parts, "..")
+	}
+	parts = append(parts, to[i:]...)
+
+	rel := strings.Join(parts, "/")
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel
+}
+
+// pythonModule returns the dotted Python module name for a slash-separated
+// path, without its extension
+func pythonModule(filename string) string {
+	module := strings.Replace(TrimExt(filename), "-", "_", -1)
+	return strings.Replace(module, "/", ".", -1)
+}
@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kerinin/protoc-gen-template/data"
+)
+
+func TestModuleImports(t *testing.T) {
+	var (
+		params  = map[string]string{"ts_module_suffix": "_grpc_pb"}
+		content = moduleImportsPlaceholder("ts")
+	)
+	for _, filename := range []string{
+		"a/foo.proto",
+		"b/foo.proto",
+		"a/foo.proto",
+		"web/out.proto",
+		"web/sub/bar-baz.proto",
+	} {
+		qualifier, err := Import("ts", &data.File{Name: filename})
+		if err != nil {
+			t.Fatalf("%s: importing: %s", filename, err)
+		}
+		content += qualifier + "X\n"
+	}
+	content += moduleImportsPlaceholder("ts")
+
+	expected := "import * as foo_grpc_pb from \"../a/foo_grpc_pb\";\n" +
+		"import * as foo_grpc_pb2 from \"../b/foo_grpc_pb\";\n" +
+		"import * as out_grpc_pb from \"./out_grpc_pb\";\n" +
+		"import * as bar_baz_grpc_pb from \"./sub/bar-baz_grpc_pb\";\n" +
+		"foo_grpc_pb.X\nfoo_grpc_pb2.X\nfoo_grpc_pb.X\nout_grpc_pb.X\nbar_baz_grpc_pb.X\n"
+	if actual := applyImports("web/out.ts", params, content); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	// The output's own module isn't qualified
+	if actual := applyImports("web/out_grpc_pb.ts", params, content); !strings.Contains(actual, "\nX\n") {
+		t.Errorf("expected own module to be unqualified, got %q", actual)
+	}
+
+	if _, err := Imports("cobol"); err == nil {
+		t.Errorf("expected error for an unknown language")
+	}
+	if _, err := Import("ts", 1); err == nil {
+		t.Errorf("expected error importing an int")
+	}
+}
+
+func TestRelativeModule(t *testing.T) {
+	for _, c := range []struct {
+		dir, module, expected string
+	}{
+		{".", "foo_pb", "./foo_pb"},
+		{".", "a/foo_pb", "./a/foo_pb"},
+		{"a", "a/foo_pb", "./foo_pb"},
+		{"a/b", "a/foo_pb", "../foo_pb"},
+		{"a/b", "c/foo_pb", "../../c/foo_pb"},
+		{"a", "a/b/foo_pb", "./b/foo_pb"},
+	} {
+		if actual := relativeModule(c.dir, c.module); actual != c.expected {
+			t.Errorf("%s -> %s: expected %q, got %q", c.dir, c.module, c.expected, actual)
+		}
+	}
+}
+
+func TestPythonImportStatement(t *testing.T) {
+	statement := importStrategies["py"].statement
+	for _, c := range []struct {
+		module, alias, expected string
+	}{
+		{"foo_pb2", "foo_pb2", "import foo_pb2"},
+		{"a.b.foo_pb2", "foo_pb2", "from a.b import foo_pb2"},
+		{"a.b.foo_pb2", "foo_pb22", "from a.b import foo_pb2 as foo_pb22"},
+	} {
+		if actual := statement("", c.module, c.alias); actual != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.module, c.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"path"
+	"strings"
+	"text/template"
+
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/pkg/errors"
+)
+
+// output collects state set by a template while it's being executed.  Its
+// functions are bound to the template set before each execution, so they
+// always refer to the output currently being generated.
+type output struct {
+	insertionPoint string
+	emitted        []*plugin.CodeGeneratorResponse_File
+	skip           bool
+}
+
+// funcs returns the template functions bound to the output
+func (o *output) funcs() template.FuncMap {
+	return template.FuncMap{
+		"insertion": o.Insertion,
+		"emit":      o.Emit,
+		"skip":      o.Skip,
+	}
+}
+
+// applyImports resolves the imports recorded in a generated file with the
+// given path, replacing its import placeholders
+func applyImports(outPath string, params map[string]string, content string) string {
+	return applyModuleImports(outPath, params, applyGoImports(content))
+}
+
+// Insertion marks the output as content to be inserted into another plugin's
+// file at the named insertion point, rather than a file of its own.  The
+// output path names the file to insert into.
+//
+// Example:
+//
+//	{{ insertion "package_scope" }}
+//	func (m *{{ .Name }}) Validate() error { ... }
+//
+// Will insert the `Validate` method wherever the target file contains
+// `// @@protoc_insertion_point(package_scope)`.
+func (o *output) Insertion(point string) string {
+	o.insertionPoint = point
+	return ""
+}
+
+// Emit adds a file with the given path and content to the response, in
+// addition to the output of the template being executed.
+//
+// Example:
+//
+//	{{ range .Messages }}
+//	{{ emit (printf "%s.json" .Name) (exec "schema" .) }}
+//	{{ end }}
+func (o *output) Emit(name, content string) (string, error) {
+	name, err := cleanOutputPath(name)
+	if err != nil {
+		return "", errors.Wrap(err, "emitting file")
+	}
+
+	o.emitted = append(o.emitted, &plugin.CodeGeneratorResponse_File{
+		Name:    &name,
+		Content: &content,
+	})
+	return "", nil
+}
+
+// Skip discards the output of the template being executed, so no file is
+// written for it.  Files added with `emit` are still written.
+//
+// Example:
+//
+//	{{ if not .Services }}{{ skip }}{{ end }}
+func (o *output) Skip() string {
+	o.skip = true
+	return ""
+}
+
+// cleanOutputPath cleans a generated file's path, failing if it refers to a
+// file outside the output directory
+func cleanOutputPath(name string) (string, error) {
+	name = path.Clean(name)
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return "", errors.Errorf("path %s is outside the output directory", name)
+	}
+	return name, nil
+}
@@ -0,0 +1,79 @@
+package generator
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// templateParam is the parameter key selecting the template path
+	templateParam = "template"
+
+	// skipEmptyParam is the parameter key enabling dropping outputs which only
+	// contain whitespace
+	skipEmptyParam = "skip_empty"
+
+	// formatParam is the parameter key selecting the default format applied
+	// to outputs
+	formatParam = "format"
+
+	// captureParam is the parameter key selecting a path to write the request
+	// to before generating files
+	captureParam = "capture"
+
+	// commentTagParam is the parameter key selecting a directive parsed from
+	// comments, repeated for each directive, ie
+	// `comment_tag=since,comment_tag=internal`
+	commentTagParam = "comment_tag"
+)
+
+// parseParameter parses the plugin parameter as comma-separated key=value
+// pairs, ie `template=templates,lang=ts,strict=true`.  An entry without a `=`
+// is treated as the template path, so a bare path remains a valid parameter.
+//
+// Keys may be given more than once, ie `template=shared,template=team`.  The
+// returned params hold the last value of each key, and values hold every value
+// of each key in order.
+func parseParameter(parameter string) (params map[string]string, values map[string][]string, err error) {
+	params = map[string]string{}
+	values = map[string][]string{}
+
+	bare := false
+	for _, entry := range strings.Split(parameter, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 1 {
+			if bare {
+				return nil, nil, errors.Errorf("template path given more than once in %q", parameter)
+			}
+			bare = true
+			parts = []string{templateParam, entry}
+		}
+
+		key := strings.TrimSpace(parts[0])
+		if key == "" {
+			return nil, nil, errors.Errorf("missing key in parameter entry %q", entry)
+		}
+		params[key] = parts[1]
+		values[key] = append(values[key], parts[1])
+	}
+
+	return params, values, nil
+}
+
+// removeParameter returns the plugin parameter without the entries with the
+// given key
+func removeParameter(parameter, key string) string {
+	entries := make([]string, 0)
+	for _, entry := range strings.Split(parameter, ",") {
+		if parts := strings.SplitN(entry, "=", 2); len(parts) == 2 && strings.TrimSpace(parts[0]) == key {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return strings.Join(entries, ",")
+}
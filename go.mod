@@ -1,13 +1,14 @@
 module github.com/kerinin/protoc-gen-template
 
-go 1.13
+go 1.16
 
 require (
-	github.com/golang/protobuf v1.5.0
+	github.com/golang/protobuf v1.5.4
 	github.com/iancoleman/strcase v0.1.3
 	github.com/kr/pretty v0.0.0-20160823170715-cfb55aafdaf3
 	github.com/kr/text v0.2.0 // indirect
 	github.com/pkg/errors v0.7.1-0.20160627222352-a2d6902c6d2a
-	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c // indirect
-	google.golang.org/protobuf v1.26.0
+	golang.org/x/tools v0.12.0
+	google.golang.org/protobuf v1.33.0
+	gopkg.in/yaml.v3 v3.0.1
 )
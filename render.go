@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	descriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/kerinin/protoc-gen-template/generator"
+	"github.com/pkg/errors"
+)
+
+const renderUsage = `Usage: protoc-gen-template render [flags] INPUT
+
+Renders templates without running protoc.  INPUT is either a
+FileDescriptorSet (as written by "protoc -o") or a CodeGeneratorRequest
+(as captured by protoc-gen-dump).
+
+Flags:
+`
+
+// render implements the `render` subcommand, which generates files from a
+// descriptor set or captured request and writes them to disk
+func render(args []string) error {
+	var (
+		flags     = flag.NewFlagSet("render", flag.ContinueOnError)
+		templates = flags.String("template", "", "Template path")
+		outDir    = flags.String("out", ".", "Output directory")
+		parameter = flags.String("param", "", "Plugin parameter, as passed by protoc")
+		generate  = flags.String("files", "", "Comma-separated files to generate (defaults to all files in a descriptor set)")
+	)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), renderUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected a single input file")
+	}
+
+	req, err := readRequest(flags.Arg(0))
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", flags.Arg(0))
+	}
+
+	if *generate != "" {
+		req.FileToGenerate = strings.Split(*generate, ",")
+	}
+	if *parameter != "" {
+		req.Parameter = parameter
+	}
+
+	// Captures are ignored, as capturing a captured request would overwrite it
+	res, err := generator.Generate(req, generator.Options{Templates: *templates, NoCapture: true})
+	if err != nil {
+		return errors.Wrap(err, "generating files")
+	}
+
+	for _, f := range res.File {
+		if err := writeFile(*outDir, f); err != nil {
+			return errors.Wrapf(err, "writing file %s", f.GetName())
+		}
+	}
+	return nil
+}
+
+// readRequest reads a CodeGeneratorRequest, or builds one from a
+// FileDescriptorSet generating every file in the set
+func readRequest(filename string) (*plugin.CodeGeneratorRequest, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: Descriptor sets are also valid (if meaningless) requests, so a
+	// request without any proto files is assumed to be a descriptor set.
+	req := &plugin.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(b, req); err == nil && len(req.ProtoFile) > 0 {
+		return req, nil
+	}
+
+	set := &descriptor.FileDescriptorSet{}
+	if err := proto.Unmarshal(b, set); err != nil {
+		return nil, errors.Wrap(err, "unmarshaling input as a request or descriptor set")
+	}
+	if len(set.File) == 0 {
+		return nil, errors.New("input doesn't describe any files")
+	}
+
+	req = &plugin.CodeGeneratorRequest{ProtoFile: set.File}
+	for _, f := range set.File {
+		req.FileToGenerate = append(req.FileToGenerate, f.GetName())
+	}
+	return req, nil
+}
+
+// writeFile writes a generated file to disk relative to outDir, applying
+// insertions to the existing file the way protoc does
+func writeFile(outDir string, f *plugin.CodeGeneratorResponse_File) error {
+	name := path.Clean(f.GetName())
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return errors.Errorf("path %s is outside the output directory", name)
+	}
+	filename := filepath.Join(outDir, filepath.FromSlash(name))
+
+	if f.InsertionPoint == nil {
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return errors.Wrap(err, "creating directory")
+		}
+		return ioutil.WriteFile(filename, []byte(f.GetContent()), 0644)
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return errors.Wrap(err, "reading insertion target")
+	}
+	content, err := insert(string(b), f.GetInsertionPoint(), f.GetContent())
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, []byte(content), 0644)
+}
+
+// insert adds content immediately before the line containing the named
+// insertion point, indenting each line to match the insertion point
+func insert(target, point, content string) (string, error) {
+	marker := fmt.Sprintf("@@protoc_insertion_point(%s)", point)
+
+	idx := strings.Index(target, marker)
+	if idx < 0 {
+		return "", errors.Errorf("insertion point %s not found", point)
+	}
+	lineStart := strings.LastIndex(target[:idx], "\n") + 1
+	line := target[lineStart:]
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+
+	buffer := &bytes.Buffer{}
+	buffer.WriteString(target[:lineStart])
+	for _, l := range strings.SplitAfter(content, "\n") {
+		if l == "" {
+			continue
+		}
+		if l != "\n" {
+			buffer.WriteString(indent)
+		}
+		buffer.WriteString(l)
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		buffer.WriteString("\n")
+	}
+	buffer.WriteString(target[lineStart:])
+	return buffer.String(), nil
+}
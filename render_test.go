@@ -0,0 +1,152 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	descriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/kerinin/protoc-gen-template/generator"
+)
+
+func TestInsert(t *testing.T) {
+	target := "package foo\n\n\t// @@protoc_insertion_point(scope)\n}\n"
+
+	actual, err := insert(target, "scope", "a\n\nb")
+	if err != nil {
+		t.Fatalf("inserting: %s", err)
+	}
+	expected := "package foo\n\n\ta\n\n\tb\n\t// @@protoc_insertion_point(scope)\n}\n"
+	if actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	if _, err := insert(target, "other", "a"); err == nil {
+		t.Errorf("expected error for missing insertion point")
+	}
+}
+
+func testDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "protoc-gen-template")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestReadRequestDescriptorSet(t *testing.T) {
+	req, err := readRequest("data/testdata/dump.pb")
+	if err != nil {
+		t.Fatalf("reading request: %s", err)
+	}
+	set := &descriptor.FileDescriptorSet{File: req.ProtoFile}
+	b, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatalf("marshaling descriptor set: %s", err)
+	}
+
+	filename := filepath.Join(testDir(t), "set.pb")
+	if err := ioutil.WriteFile(filename, b, 0644); err != nil {
+		t.Fatalf("writing descriptor set: %s", err)
+	}
+
+	actual, err := readRequest(filename)
+	if err != nil {
+		t.Fatalf("reading request: %s", err)
+	}
+	if len(actual.ProtoFile) != len(req.ProtoFile) {
+		t.Errorf("expected %d proto files, got %d", len(req.ProtoFile), len(actual.ProtoFile))
+	}
+	if len(actual.FileToGenerate) != len(req.ProtoFile) {
+		t.Errorf("expected %d files to generate, got %d", len(req.ProtoFile), len(actual.FileToGenerate))
+	}
+}
+
+func TestRender(t *testing.T) {
+	var (
+		templateDir = testDir(t)
+		outDir      = testDir(t)
+	)
+	err := ioutil.WriteFile(filepath.Join(templateDir, "{{.Package}}.txt.tmpl"), []byte("{{.Data.Params.lang}}"), 0644)
+	if err != nil {
+		t.Fatalf("writing template: %s", err)
+	}
+
+	err = render([]string{"-template", templateDir, "-out", outDir, "-param", "lang=ts", "data/testdata/dump.pb"})
+	if err != nil {
+		t.Fatalf("rendering: %s", err)
+	}
+
+	for _, name := range []string{"testv2.txt", "testv3.txt"} {
+		b, err := ioutil.ReadFile(filepath.Join(outDir, name))
+		if err != nil {
+			t.Errorf("reading %s: %s", name, err)
+		} else if string(b) != "ts" {
+			t.Errorf("%s: expected %q, got %q", name, "ts", string(b))
+		}
+	}
+}
+
+func TestRenderCaptured(t *testing.T) {
+	var (
+		templateDir = testDir(t)
+		captured    = filepath.Join(testDir(t), "request.pb")
+	)
+	err := ioutil.WriteFile(filepath.Join(templateDir, "{{.Package}}.txt.tmpl"), []byte("{{.Data.Params.lang}}"), 0644)
+	if err != nil {
+		t.Fatalf("writing template: %s", err)
+	}
+
+	req, err := readRequest("data/testdata/dump.pb")
+	if err != nil {
+		t.Fatalf("reading request: %s", err)
+	}
+	req.Parameter = proto.String("lang=ts,capture=" + captured)
+	if _, err := generator.Generate(req, generator.Options{Templates: templateDir}); err != nil {
+		t.Fatalf("capturing: %s", err)
+	}
+	before, err := ioutil.ReadFile(captured)
+	if err != nil {
+		t.Fatalf("reading captured request: %s", err)
+	}
+	req = &plugin.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(before, req); err != nil {
+		t.Fatalf("unmarshaling captured request: %s", err)
+	}
+	if req.GetParameter() != "lang=ts" {
+		t.Errorf("expected captured parameter %q, got %q", "lang=ts", req.GetParameter())
+	}
+
+	outDir := testDir(t)
+	err = render([]string{"-template", templateDir, "-out", outDir, "-param", "lang=go,capture=" + captured, "-files", "protoc-gen-template/data/testdata/testv3.proto", captured})
+	if err != nil {
+		t.Fatalf("rendering captured request: %s", err)
+	}
+	after, err := ioutil.ReadFile(captured)
+	if err != nil {
+		t.Fatalf("reading captured request: %s", err)
+	}
+	if string(before) != string(after) {
+		t.Errorf("expected rendering not to overwrite the captured request")
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(outDir, "testv3.txt")); err != nil || string(b) != "go" {
+		t.Errorf("expected testv3.txt containing %q, got %q (%v)", "go", string(b), err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "testv2.txt")); err == nil {
+		t.Errorf("expected only testv3.txt to be rendered")
+	}
+}
+
+func TestWriteFileOutsideOutput(t *testing.T) {
+	outDir := testDir(t)
+	for _, name := range []string{"../out.txt", "/tmp/out.txt"} {
+		err := writeFile(outDir, &plugin.CodeGeneratorResponse_File{Name: proto.String(name), Content: proto.String("")})
+		if err == nil {
+			t.Errorf("%s: expected error writing outside the output directory", name)
+		}
+	}
+}